@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"lemin/internal"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Analyze prints the structure of a farm for "lemin analyze", as a report or
+// as JSON with --json.
+func Analyze(args []string) {
+	asJSON := false
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		if arg == "--json" {
+			asJSON = true
+			return true
+		}
+		return false
+	})
+	positional := internal.ParseFlags(args)
+	if len(positional) != 1 {
+		internal.Log("usage: lemin analyze [--json] <file>", "error")
+		os.Exit(0)
+	}
+
+	file, err := os.Open(positional[0])
+	if err != nil {
+		internal.Log("file does not exist", "error")
+		os.Exit(0)
+	}
+	defer file.Close()
+
+	internal.SetQuiet(true) // only the report is printed
+	a, err := internal.Analyze(file)
+	if err != nil {
+		internal.Log(err.Error(), "error")
+		os.Exit(0)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(a)
+		return
+	}
+	printAnalysis(a)
+}
+
+func printAnalysis(a *internal.Analysis) {
+	fmt.Printf("rooms:               %d\n", a.Rooms)
+	fmt.Printf("tunnels:             %d\n", a.Tunnels)
+
+	var degrees []string
+	for _, d := range a.Degrees {
+		degrees = append(degrees, fmt.Sprintf("%s of degree %d", plural(d.Rooms, "room"), d.Degree))
+	}
+	fmt.Printf("degrees:             %s\n", strings.Join(degrees, ", "))
+
+	var sizes []string
+	for _, size := range a.Components {
+		sizes = append(sizes, fmt.Sprint(size))
+	}
+	fmt.Printf("components:          %d (%s rooms)\n", len(a.Components), strings.Join(sizes, ", "))
+	fmt.Printf("unreachable rooms:   %s\n", roomList(a.Unreachable))
+	fmt.Printf("dead ends:           %s\n", roomList(a.DeadEnds))
+
+	if a.ShortestPath == -1 {
+		fmt.Println("shortest path:       none, no end room can be reached")
+		return
+	}
+	fmt.Printf("shortest path:       %s\n", plural(a.ShortestPath, "move"))
+	fmt.Printf("disjoint paths:      %d\n", a.MaxFlow)
+	fmt.Printf("articulation rooms:  %s\n", roomList(a.Articulation))
+
+	fmt.Printf("min vertex cut:      %s\n", roomList(cutList(a.MinCutRooms, a.MinCutTunnels)))
+}
+
+// cutList names the rooms and tunnels of a cut, one-way tunnels as a>b.
+func cutList(rooms []string, tunnels []internal.Tunnel) []string {
+	cut := slices.Clone(rooms)
+	for _, t := range tunnels {
+		sep := "-"
+		if t.OneWay {
+			sep = ">"
+		}
+		cut = append(cut, t.From+sep+t.To)
+	}
+	return cut
+}
+
+// plural formats a count of things, "1 room" or "2 rooms".
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// roomList joins names, "none" when there are none.
+func roomList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"lemin/internal"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// Bench runs the solver on every farm (*.txt) in a directory and prints a table
+// of the results, to spot speed or quality regressions between versions.
+func Bench(args []string) {
+	positional := internal.ParseFlags(args)
+	if len(positional) != 1 {
+		internal.Log("usage: lemin bench [flags] <directory>", "error")
+		os.Exit(0)
+	}
+
+	files, err := filepath.Glob(filepath.Join(positional[0], "*.txt"))
+	if err != nil || len(files) == 0 {
+		internal.Log("no farms found in "+positional[0], "error")
+		os.Exit(0)
+	}
+
+	internal.SetQuiet(true) // only the table is printed
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tROOMS\tTUNNELS\tANTS\tTURNS\tLOWER BOUND\tTIME")
+	for _, file := range files {
+		ctx, cancel := internal.SolverContext()
+		result, err := internal.SolveFile(ctx, file)
+		cancel()
+
+		name := filepath.Base(file)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\terror: %v\n", name, err)
+			continue
+		}
+
+		turns := fmt.Sprint(result.Turns)
+		if result.TimedOut {
+			turns += "*" // may be suboptimal
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\t%s\n", name, result.Rooms, result.Tunnels,
+			result.Ants, turns, result.LowerBound, result.Elapsed)
+	}
+	w.Flush()
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"lemin/internal"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Bottleneck prints the minimum cut of a farm and the new tunnels that would
+// save the most turns for "lemin bottleneck", as a table or as JSON with
+// --json. --candidates bounds how many tunnels are tried.
+func Bottleneck(args []string) {
+	asJSON := false
+	limit := 200
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--json":
+			asJSON = true
+		case "--candidates":
+			n, err := 0, error(nil)
+			if i+1 < len(args) {
+				n, err = strconv.Atoi(args[i+1])
+				i++
+			}
+			if err != nil || n <= 0 {
+				internal.Log("candidates must be a number > 0", "error")
+				os.Exit(0)
+			}
+			limit = n
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	positional := internal.ParseFlags(rest)
+	if len(positional) != 1 {
+		internal.Log("usage: lemin bottleneck [--json] [--candidates n] [flags] <file>", "error")
+		os.Exit(0)
+	}
+
+	file, err := os.Open(positional[0])
+	if err != nil {
+		internal.Log("file does not exist", "error")
+		os.Exit(0)
+	}
+	defer file.Close()
+
+	internal.SetQuiet(true) // only the report is printed
+	ctx, cancel := internal.SolverContext()
+	defer cancel()
+	report, err := internal.Bottleneck(ctx, file, limit)
+	if err != nil {
+		internal.Log(err.Error(), "error")
+		os.Exit(0)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+
+	fmt.Printf("%d ants, about %d turns through %s\n", report.Ants, report.Turns, plural(report.MaxFlow, "disjoint path"))
+	fmt.Printf("min cut: %s\n", roomList(cutList(report.CutRooms, report.CutTunnels)))
+	if len(report.Suggestions) == 0 {
+		fmt.Printf("none of the %s tried saves a turn\n", plural(report.Tried, "tunnel"))
+		return
+	}
+
+	fmt.Printf("%s out of %d tried save turns:\n\n", plural(len(report.Suggestions), "tunnel"), report.Tried)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tTUNNEL\tTURNS\tSAVED\tPATHS")
+	for i, s := range report.Suggestions {
+		fmt.Fprintf(w, "%d\t%s-%s\t%d\t%d\t%d\n", i+1, s.From, s.To, s.Turns, s.Saved, s.MaxFlow)
+	}
+	w.Flush()
+}
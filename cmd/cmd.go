@@ -3,18 +3,48 @@ package cmd
 import (
 	"fmt"
 	"lemin/internal"
+	"os"
 	"time"
 )
 
 func Cmd() {
+	// subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			Bench(os.Args[2:])
+			return
+		case "analyze":
+			Analyze(os.Args[2:])
+			return
+		case "bottleneck":
+			Bottleneck(os.Args[2:])
+			return
+		case "sweep":
+			Sweep(os.Args[2:])
+			return
+		case "serve":
+			Serve(os.Args[2:])
+			return
+		}
+	}
+
 	start := time.Now()
 
 	file := internal.GetFile()
+	ctx, cancel := internal.SolverContext()
+	defer cancel()
+
 	internal.ValidateFileFormat(file)
 	internal.ValidateConnectivity()
-	internal.FindAllPaths()
-	internal.FindBestPaths()
-	internal.Simulate()
+	internal.PruneGraph()
+	internal.BuildGraph()
+	internal.FindAllPaths(ctx)
+	if internal.Query(ctx) {
+		return
+	}
+	internal.FindBestPaths(ctx)
+	internal.Simulate(ctx)
 	internal.CreateJson()
 	internal.RunVisualizer()
 
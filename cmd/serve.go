@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lemin/internal"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:embed view.html
+var viewPage []byte
+
+// maxRequestSize caps request bodies, farms of a few hundred thousand rooms
+// still fit.
+const maxRequestSize = 16 << 20
+
+// serveTimeout bounds every request, waiting for the solver included, so one
+// dense farm cannot hold the server. --timeout can only shorten it.
+var serveTimeout = 10 * time.Second
+
+// Serve runs the HTTP server of "lemin serve". The solver flags apply to every
+// request, --addr sets where it listens.
+func Serve(args []string) {
+	addr := "127.0.0.1:8080"
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--addr" && i+1 < len(args):
+			addr = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--addr="):
+			addr = strings.TrimPrefix(args[i], "--addr=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	if positional := internal.ParseFlags(rest); len(positional) > 0 {
+		internal.Log("usage: lemin serve [--addr host:port] [flags]", "error")
+		os.Exit(0)
+	}
+
+	internal.SetQuiet(true) // requests are answered, not logged
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Println("listening on http://" + addr)
+	if err := server.ListenAndServe(); err != nil {
+		internal.Log(err.Error(), "error")
+	}
+}
+
+// newServeMux routes the endpoints of the server. Every handler goes through
+// the library API, which serializes the calls, so concurrent requests cannot
+// see each other's farm. A request still waiting for its turn when it times
+// out or its client leaves is answered 503.
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /solve", handleSolve)
+	mux.HandleFunc("POST /validate", handleValidate)
+	mux.HandleFunc("POST /check", handleCheck)
+	mux.HandleFunc("GET /view", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewPage)
+	})
+	return mux
+}
+
+// serveRequest is the JSON body of the POST endpoints. A body that is not
+// JSON is taken as the farm itself.
+type serveRequest struct {
+	Farm    string   `json:"farm"`
+	Moves   []string `json:"moves"`   // for /check, one line per turn
+	Timeout string   `json:"timeout"` // for /solve, such as 500ms
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request) (serveRequest, error) {
+	var req serveRequest
+	body := http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			return req, errors.New("invalid JSON: " + err.Error())
+		}
+		return req, nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return req, err
+	}
+	req.Farm = string(data)
+	req.Timeout = r.URL.Query().Get("timeout")
+	return req, nil
+}
+
+func handleSolve(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the request may ask for a shorter budget than --timeout, not a longer one
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	if req.Timeout != "" {
+		d, err := time.ParseDuration(req.Timeout)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("timeout must be a positive duration such as 500ms or 10s"))
+			return
+		}
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	result, err := internal.Solve(ctx, strings.NewReader(req.Farm))
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func handleValidate(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	farm, err := internal.ValidateContext(ctx, strings.NewReader(req.Farm))
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, farm)
+}
+
+// checkResponse tells whether a schedule follows the rules. A broken rule is
+// not a failed request, the answer is 200 either way.
+type checkResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func handleCheck(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	if err := internal.CheckContext(ctx, strings.NewReader(req.Farm), req.Moves); err != nil {
+		if status := errorStatus(err); status != http.StatusBadRequest {
+			writeError(w, status, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, checkResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, checkResponse{Valid: true})
+}
+
+// requestContext bounds a request by serveTimeout, and by --timeout when it
+// is shorter.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(r.Context(), serveTimeout)
+	solverCtx, solverCancel := internal.WithSolverTimeout(ctx)
+	return solverCtx, func() {
+		solverCancel()
+		cancel()
+	}
+}
+
+// errorStatus is 503 for a request that ran out of time before the solver was
+// free, 400 for a farm the solver refused.
+func errorStatus(err error) int {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
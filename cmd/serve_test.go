@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lemin/internal"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	internal.SetQuiet(true)
+	os.Exit(m.Run())
+}
+
+func readFarm(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "tests", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// post sends body to the server and decodes the JSON answer into v.
+func post(t *testing.T, server *httptest.Server, path, contentType, body string, v any) int {
+	t.Helper()
+	res, err := http.Post(server.URL+path, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode
+}
+
+func TestServe(t *testing.T) {
+	server := httptest.NewServer(newServeMux())
+	defer server.Close()
+	farm := readFarm(t, "test.txt")
+
+	var result internal.Result
+	if status := post(t, server, "/solve", "text/plain", farm, &result); status != http.StatusOK {
+		t.Fatalf("/solve answered %d", status)
+	}
+	if result.Turns != 6 || len(result.Moves) != 6 {
+		t.Errorf("/solve: got %d turns and %d lines of moves, want 6", result.Turns, len(result.Moves))
+	}
+
+	body, _ := json.Marshal(map[string]any{"farm": farm, "moves": result.Moves})
+	var check map[string]any
+	post(t, server, "/check", "application/json", string(body), &check)
+	if check["valid"] != true {
+		t.Errorf("/check rejected the schedule of /solve: %v", check)
+	}
+
+	body, _ = json.Marshal(map[string]any{"farm": farm, "moves": []string{"L1-3"}})
+	post(t, server, "/check", "application/json", string(body), &check)
+	if check["valid"] != false || check["error"] == "" {
+		t.Errorf("/check accepted a broken schedule: %v", check)
+	}
+
+	var validated internal.Farm
+	body, _ = json.Marshal(map[string]string{"farm": farm})
+	if status := post(t, server, "/validate", "application/json", string(body), &validated); status != http.StatusOK {
+		t.Fatalf("/validate answered %d", status)
+	}
+	if len(validated.Rooms) != 4 || len(validated.Tunnels) != 3 || validated.Starts[0] != "0" {
+		t.Errorf("/validate: got %+v", validated)
+	}
+
+	var failure map[string]string
+	if status := post(t, server, "/validate", "text/plain", "0\n", &failure); status != http.StatusBadRequest {
+		t.Errorf("/validate answered %d to a broken farm", status)
+	}
+	if failure["error"] != "line 1: number of ants must be > 0" {
+		t.Errorf("/validate: got error %q", failure["error"])
+	}
+
+	res, err := http.Get(server.URL + "/view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("/view answered %d with %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+}
+
+// TestServeConcurrent solves different farms at the same time, none of them
+// may get the answer of another.
+func TestServeConcurrent(t *testing.T) {
+	server := httptest.NewServer(newServeMux())
+	defer server.Close()
+
+	want := map[string]int{"test.txt": 6, "test3.txt": 11, "test5.txt": 7, "multi.txt": 5}
+	var wg sync.WaitGroup
+	for name, turns := range want {
+		farm := readFarm(t, name)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := http.Post(server.URL+"/solve", "text/plain", strings.NewReader(farm))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer res.Body.Close()
+
+				var result internal.Result
+				if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+					t.Error(err)
+				} else if result.Turns != turns {
+					t.Errorf("%s: got %d turns, want %d", name, result.Turns, turns)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// cliqueFarm links every room to every other one, a search of it only ends
+// with the time limit.
+func cliqueFarm(rooms int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "10\n##start\ns 0 0\n##end\ne %d 0\n", rooms+1)
+	for i := range rooms {
+		fmt.Fprintf(&b, "r%d %d %d\n", i, i+1, i%2+1)
+	}
+	fmt.Fprintf(&b, "s-r0\ns-r1\nr%d-e\n", rooms-1)
+	for i := range rooms {
+		for j := i + 1; j < rooms; j++ {
+			fmt.Fprintf(&b, "r%d-r%d\n", i, j)
+		}
+	}
+	return b.String()
+}
+
+// TestServeBusy keeps the solver busy with a dense farm: a request given up
+// while waiting for it is answered 503 at once, and the dense farm itself is
+// stopped by serveTimeout.
+func TestServeBusy(t *testing.T) {
+	serveTimeout = 500 * time.Millisecond
+	defer func() { serveTimeout = 10 * time.Second }()
+	mux := newServeMux()
+
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/solve", strings.NewReader(cliqueFarm(14))))
+		slow <- rec
+	}()
+	time.Sleep(100 * time.Millisecond) // the dense farm takes the solver
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/validate", strings.NewReader(readFarm(t, "test.txt"))).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/validate while busy answered %d: %s", rec.Code, rec.Body)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("/validate waited %s after its client left", elapsed)
+	}
+
+	select {
+	case rec := <-slow:
+		var result internal.Result
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK || !result.TimedOut {
+			t.Errorf("dense /solve answered %d, timed out %v", rec.Code, result.TimedOut)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dense /solve ignored serveTimeout")
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"lemin/internal"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Sweep writes the turns of a farm for a range of ant counts as CSV for
+// "lemin sweep". --counts takes a range such as 1-100 or a list such as
+// 1,5,10, --out names the file to write instead of stdout.
+func Sweep(args []string) {
+	var counts []int
+	out := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--counts" && i+1 < len(args):
+			parsed, err := parseCounts(args[i+1])
+			if err != nil {
+				internal.Log("counts: "+err.Error(), "error")
+				os.Exit(0)
+			}
+			counts = parsed
+			i++
+		case args[i] == "--out" && i+1 < len(args):
+			out = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	positional := internal.ParseFlags(rest)
+	if len(positional) != 1 {
+		internal.Log("usage: lemin sweep [--counts 1-100|1,5,10] [--out file.csv] [flags] <file>", "error")
+		os.Exit(0)
+	}
+
+	file, err := os.Open(positional[0])
+	if err != nil {
+		internal.Log("file does not exist", "error")
+		os.Exit(0)
+	}
+	defer file.Close()
+
+	internal.SetQuiet(true) // only the CSV is printed
+	ctx, cancel := internal.SolverContext()
+	defer cancel()
+	rows, err := internal.Sweep(ctx, file, counts)
+	if err != nil {
+		internal.Log(err.Error(), "error")
+		os.Exit(0)
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			internal.Log("could not create "+out+": "+err.Error(), "error")
+			os.Exit(0)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeSweep(w, rows); err != nil {
+		internal.Log("failed to write the sweep: "+err.Error(), "error")
+	}
+}
+
+// writeSweep writes one CSV line per ant count. The quotas of the paths are
+// joined with ";" in one column.
+func writeSweep(w io.Writer, rows []internal.SweepRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ants", "turns", "best_turns", "paths", "quotas"})
+	for _, row := range rows {
+		quotas := make([]string, len(row.Quotas))
+		for i, q := range row.Quotas {
+			quotas[i] = strconv.Itoa(q)
+		}
+		cw.Write([]string{
+			strconv.Itoa(row.Ants),
+			strconv.Itoa(row.Turns),
+			strconv.Itoa(row.BestTurns),
+			strconv.Itoa(len(row.Quotas)),
+			strings.Join(quotas, ";"),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseCounts reads "1-100" or "1,5,10".
+func parseCounts(spec string) ([]int, error) {
+	if from, to, ok := strings.Cut(spec, "-"); ok {
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || lo <= 0 || hi < lo {
+			return nil, fmt.Errorf("invalid range %q, want <from>-<to>", spec)
+		}
+		var counts []int
+		for n := lo; n <= hi; n++ {
+			counts = append(counts, n)
+		}
+		return counts, nil
+	}
+
+	var counts []int
+	for _, field := range strings.Split(spec, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return nil, errors.New("invalid ant count " + strconv.Quote(field))
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
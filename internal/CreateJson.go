@@ -6,20 +6,21 @@ import (
 )
 
 type SimulationDump struct {
-	Start string `json:"start"`
-	Rooms []Room `json:"rooms"`
-	Moves []Move `json:"moves"`
+	Start  string   `json:"start"` // the first start room, kept for older viewers
+	Starts []string `json:"starts"`
+	Ends   []string `json:"ends"`
+	Rooms  []Room   `json:"rooms"`
+	Moves  []Move   `json:"moves"`
 }
 
 func CreateJson() {
 	if visualizer {
 		dump := SimulationDump{
-			Start: startRoom,
-			Rooms: make([]Room, 0, len(rooms)),
-			Moves: allMoves,
-		}
-		for _, r := range rooms {
-			dump.Rooms = append(dump.Rooms, r)
+			Start:  startRoom,
+			Starts: startRooms,
+			Ends:   endRooms,
+			Rooms:  displayRooms(),
+			Moves:  allMoves,
 		}
 
 		f, err := os.Create("simulation.json")
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sort"
+)
+
+// Analysis describes the structure of a farm, to judge how hard it is before
+// solving it.
+type Analysis struct {
+	Rooms         int           `json:"rooms"`
+	Tunnels       int           `json:"tunnels"`
+	Degrees       []DegreeCount `json:"degrees"`         // by increasing degree
+	Components    []int         `json:"components"`      // room count of every connected component, largest first
+	Unreachable   []string      `json:"unreachable"`     // rooms no ant can get to from a start room
+	DeadEnds      []string      `json:"dead_ends"`       // rooms with a single neighbour, besides start and end rooms
+	ShortestPath  int           `json:"shortest_path"`   // moves from a start room to an end room, -1 when there is no way
+	MaxFlow       int           `json:"max_flow"`        // room-disjoint paths from the start rooms to the end rooms
+	Articulation  []string      `json:"articulation"`    // rooms every path goes through
+	MinCutRooms   []string      `json:"min_cut_rooms"`   // a smallest set of rooms cutting every path,
+	MinCutTunnels []Tunnel      `json:"min_cut_tunnels"` // with the tunnels from a start room straight into an end room
+}
+
+// DegreeCount is how many rooms have Degree neighbours. Tunnels count both
+// ways, one-way or not.
+type DegreeCount struct {
+	Degree int `json:"degree"`
+	Rooms  int `json:"rooms"`
+}
+
+// Analyze parses a farm and describes its structure. The farm does not need
+// to be solvable: an unreachable end room is reported, not an error.
+func Analyze(r io.Reader) (*Analysis, error) {
+	lockSolver(context.Background()) // cannot fail without a deadline
+	defer unlockSolver()
+
+	resetFarm()
+	if err := parseFarm(r); err != nil {
+		return nil, err
+	}
+	BuildGraph() // not pruned, dead ends are part of the answer
+	return analyze(), nil
+}
+
+// analyze describes the farm in graph.
+func analyze() *Analysis {
+	a := &Analysis{
+		Rooms:        len(graph.Names),
+		Tunnels:      countTunnels(),
+		ShortestPath: -1,
+	}
+
+	// degrees and dead ends, on the rooms linked either way
+	linked := make([][]int, len(graph.Names))
+	degrees := make(map[int]int)
+	for id := range graph.Names {
+		seen := make(map[int]bool)
+		for _, neighbor := range graph.Neighbors(id) {
+			seen[neighbor] = true
+		}
+		for _, neighbor := range graph.Incoming(id) {
+			seen[neighbor] = true
+		}
+		for neighbor := range seen {
+			linked[id] = append(linked[id], neighbor)
+		}
+		degrees[len(seen)]++
+		if len(seen) <= 1 && !graph.IsStart[id] && !graph.IsEnd[id] {
+			a.DeadEnds = append(a.DeadEnds, graph.Names[id])
+		}
+	}
+	for degree, count := range degrees {
+		a.Degrees = append(a.Degrees, DegreeCount{Degree: degree, Rooms: count})
+	}
+	sort.Slice(a.Degrees, func(i, j int) bool { return a.Degrees[i].Degree < a.Degrees[j].Degree })
+
+	// connected components
+	component := make([]int, len(graph.Names))
+	for i := range component {
+		component[i] = -1
+	}
+	for id := range graph.Names {
+		if component[id] != -1 {
+			continue
+		}
+		size := 0
+		component[id] = len(a.Components)
+		stack := []int{id}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			size++
+			for _, neighbor := range linked[current] {
+				if component[neighbor] == -1 {
+					component[neighbor] = component[id]
+					stack = append(stack, neighbor)
+				}
+			}
+		}
+		a.Components = append(a.Components, size)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(a.Components)))
+
+	// ants stop in the first end room they reach, so they cannot get past one
+	dist := graph.distances(graph.Starts, graph.IsEnd)
+	for id, d := range dist {
+		if d == -1 {
+			a.Unreachable = append(a.Unreachable, graph.Names[id])
+		}
+	}
+
+	shortest := graph.shortestPath(graph.Starts, graph.Ends)
+	if shortest == nil {
+		return a
+	}
+	a.ShortestPath = len(shortest) - 1
+
+	// every path goes through a room exactly when blocking it leaves no path,
+	// and only the rooms of one path have to be tried
+	blocked := make([]bool, len(graph.Names))
+	for _, id := range shortest[1 : len(shortest)-1] {
+		blocked[id] = true
+		if graph.without(nil, blocked).shortestPath(graph.Starts, graph.Ends) == nil {
+			a.Articulation = append(a.Articulation, graph.Names[id])
+		}
+		blocked[id] = false
+	}
+	sort.Strings(a.Articulation)
+
+	net := newFlowNetwork(graph)
+	net.widenTunnels(graph)
+	for net.augment() {
+		a.MaxFlow++
+	}
+	a.MinCutRooms, a.MinCutTunnels = net.minCut(graph)
+	return a
+}
+
+// widenTunnels lets any number of paths through a tunnel, so a minimum cut is
+// made of rooms. A tunnel leading from a start room straight into an end room
+// has no room in between to cut and keeps its capacity.
+func (n *flowNetwork) widenTunnels(g *Graph) {
+	for node := 1; node < n.source; node += 2 { // out nodes
+		for i, e := range n.adj[node] {
+			if e.orig == 0 || e.to >= n.source {
+				continue
+			}
+			if g.IsStart[node/2] && g.IsEnd[e.to/2] {
+				continue
+			}
+			n.adj[node][i].cap = len(g.Names)
+			n.adj[node][i].orig = len(g.Names)
+		}
+	}
+}
+
+// minCut returns the rooms and tunnels of a minimum cut once the flow is
+// maximal: the arcs leading from the nodes still reachable from the source in
+// the residual network to the other ones. The cut is the one closest to the
+// start rooms.
+func (n *flowNetwork) minCut(g *Graph) ([]string, []Tunnel) {
+	reached := n.residualReach()
+
+	var cutRooms []string
+	var cutTunnels []Tunnel
+	for node, edges := range n.adj {
+		if !reached[node] || node >= n.source {
+			continue
+		}
+		for _, e := range edges {
+			if e.orig == 0 || reached[e.to] || e.to >= n.source {
+				continue
+			}
+			if node%2 == 0 { // in -> out: the room itself
+				cutRooms = append(cutRooms, g.Names[node/2])
+			} else {
+				from, to := g.Names[node/2], g.Names[e.to/2]
+				cutTunnels = append(cutTunnels, Tunnel{From: from, To: to, OneWay: oneWay[[2]string{from, to}]})
+			}
+		}
+	}
+	sort.Strings(cutRooms)
+	sort.Slice(cutTunnels, func(i, j int) bool {
+		a, b := cutTunnels[i], cutTunnels[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return cutRooms, cutTunnels
+}
+
+// residualReach marks the nodes the source still reaches in the residual
+// network, the source side of the minimum cut once the flow is maximal.
+func (n *flowNetwork) residualReach() []bool {
+	reached := make([]bool, len(n.adj))
+	reached[n.source] = true
+	queue := []int{n.source}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range n.adj[current] {
+			if e.cap > 0 && !reached[e.to] {
+				reached[e.to] = true
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return reached
+}
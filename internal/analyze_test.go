@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	for _, tc := range []struct {
+		file string
+		want Analysis
+	}{
+		{"test.txt", Analysis{
+			Rooms: 4, Tunnels: 3,
+			Degrees:      []DegreeCount{{1, 2}, {2, 2}},
+			Components:   []int{4},
+			ShortestPath: 3, MaxFlow: 1,
+			Articulation: []string{"2", "3"},
+			MinCutRooms:  []string{"2"},
+		}},
+		{"multi.txt", Analysis{
+			Rooms: 9, Tunnels: 10,
+			Degrees:      []DegreeCount{{2, 8}, {4, 1}},
+			Components:   []int{9},
+			ShortestPath: 2, MaxFlow: 3,
+			MinCutRooms: []string{"a", "d", "m"},
+		}},
+		{"directed.txt", Analysis{
+			Rooms: 6, Tunnels: 8,
+			Degrees:      []DegreeCount{{2, 4}, {3, 2}},
+			Components:   []int{6},
+			Unreachable:  []string{"d"},
+			ShortestPath: 2, MaxFlow: 1,
+			Articulation: []string{"c"},
+			MinCutRooms:  []string{"c"},
+		}},
+	} {
+		file, err := os.Open(filepath.Join("..", "tests", tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Analyze(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.file, err)
+		}
+		if !reflect.DeepEqual(*got, tc.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", tc.file, *got, tc.want)
+		}
+	}
+}
+
+// TestAnalyzeBroken analyzes a farm the solver rejects: the end room is cut
+// off and a dead end hangs off the start room.
+func TestAnalyzeBroken(t *testing.T) {
+	farm := `3
+##start
+s 0 0
+a 1 0
+b 2 0
+x 1 1
+##end
+e 3 0
+s-a
+a-b
+s-x
+`
+	got, err := Analyze(strings.NewReader(farm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Analysis{
+		Rooms: 5, Tunnels: 3,
+		Degrees:      []DegreeCount{{0, 1}, {1, 2}, {2, 2}},
+		Components:   []int{4, 1},
+		Unreachable:  []string{"e"},
+		DeadEnds:     []string{"b", "x"},
+		ShortestPath: -1,
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("got  %+v\nwant %+v", *got, want)
+	}
+}
+
+func TestBottleneck(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "tests", "test.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	report, err := Bottleneck(context.Background(), file, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Turns != 6 || report.MaxFlow != 1 || !reflect.DeepEqual(report.CutRooms, []string{"2"}) {
+		t.Errorf("got %d turns and cut %v of %d rooms, want 6 turns and cut [2]", report.Turns, report.CutRooms, report.MaxFlow)
+	}
+	want := []Suggestion{
+		{From: "0", To: "1", Turns: 3, Saved: 3, MaxFlow: 2},
+		{From: "0", To: "3", Turns: 5, Saved: 1, MaxFlow: 1},
+		{From: "1", To: "2", Turns: 5, Saved: 1, MaxFlow: 1},
+	}
+	if !reflect.DeepEqual(report.Suggestions, want) {
+		t.Errorf("got suggestions %+v, want %+v", report.Suggestions, want)
+	}
+
+	// the search is bounded, the most promising tunnel is tried first
+	file.Seek(0, 0)
+	report, err = Bottleneck(context.Background(), file, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tried != 1 || len(report.Suggestions) != 1 || report.Suggestions[0] != want[0] {
+		t.Errorf("with one candidate: tried %d, got %+v", report.Tried, report.Suggestions)
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// antGroup is a set of ants that spawn and arrive under the same constraints.
+// Without per-start ant counts or #group lines all ants form one group that
+// may use any start and end room.
+type antGroup struct {
+	ants  int
+	start string // the start room the ants spawn in, "" for any
+	end   string // the end room the ants have to reach, "" for any
+}
+
+// uses reports whether the ants of the group may be sent through path.
+func (g antGroup) uses(path []int) bool {
+	if g.start != "" && graph.Names[path[0]] != g.start {
+		return false
+	}
+	return g.end == "" || graph.Names[path[len(path)-1]] == g.end
+}
+
+// buildAntGroups checks the "##start <ants>" counts and the #group lines and
+// splits the ants into groups. Either every start room has a count and they
+// add up to the number of ants, or none has one. The same goes for #group
+// lines, which name the start room themselves instead of using the counts.
+func buildAntGroups() error {
+	if len(groupLines) > 0 {
+		return buildGoalGroups()
+	}
+	if len(startAnts) == 0 {
+		antGroups = []antGroup{{ants: ants}}
+		return nil
+	}
+
+	total := 0
+	for _, room := range startRooms {
+		n, ok := startAnts[room]
+		if !ok {
+			return errors.New("no ant count for start room: " + room)
+		}
+		antGroups = append(antGroups, antGroup{ants: n, start: room})
+		total += n
+	}
+	if total != ants {
+		return fmt.Errorf("ant counts of the start rooms add up to %d, not %d", total, ants)
+	}
+	return nil
+}
+
+// buildGoalGroups turns the #group lines into ant groups.
+func buildGoalGroups() error {
+	if len(startAnts) > 0 {
+		return errors.New("#group lines and ##start ant counts cannot be mixed, name the start room in #group instead")
+	}
+
+	total := 0
+	for _, group := range groupLines {
+		if !isEndRoom(group.end) {
+			return errors.New("group destination is not an end room: " + group.end)
+		}
+		if group.start != "" && !isStartRoom(group.start) {
+			return errors.New("group start is not a start room: " + group.start)
+		}
+		total += group.ants
+	}
+	if total != ants {
+		return fmt.Errorf("group sizes add up to %d, not %d", total, ants)
+	}
+	antGroups = groupLines
+	return nil
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// benchFarm is one farm the benchmarks run on.
+type benchFarm struct {
+	name string
+	data []byte
+}
+
+// benchFarms returns the sample farms in tests/ plus a few generated large ones.
+func benchFarms(b *testing.B) []benchFarm {
+	b.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "tests", "*.txt"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	generated := []string{
+		writeLadderFarm(b, 4, 250, 100),   // 1k rooms
+		writeLadderFarm(b, 10, 1000, 500), // 10k rooms
+	}
+	names := []string{"ladder1k", "ladder10k"}
+
+	var farms []benchFarm
+	for i, file := range append(files, generated...) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".txt")
+		if i >= len(files) {
+			name = names[i-len(files)]
+		}
+		farms = append(farms, benchFarm{name: name, data: data})
+	}
+	return farms
+}
+
+// prepareFarm runs the pipeline on farm up to the path search.
+func prepareFarm(b *testing.B, farm benchFarm) {
+	b.Helper()
+	resetFarm()
+	if err := parseFarm(bytes.NewReader(farm.data)); err != nil {
+		b.Fatal(err)
+	}
+	if err := checkConnectivity(); err != nil {
+		b.Fatal(err)
+	}
+	PruneGraph()
+	BuildGraph()
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, farm := range benchFarms(b) {
+		b.Run(farm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				resetFarm()
+				if err := parseFarm(bytes.NewReader(farm.data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFindAllPaths(b *testing.B) {
+	for _, farm := range benchFarms(b) {
+		b.Run(farm.name, func(b *testing.B) {
+			prepareFarm(b, farm)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FindAllPaths(context.Background())
+			}
+		})
+	}
+}
+
+func BenchmarkComputeAntsPerPath(b *testing.B) {
+	for _, farm := range benchFarms(b) {
+		b.Run(farm.name, func(b *testing.B) {
+			prepareFarm(b, farm)
+			FindAllPaths(context.Background())
+			FindBestPaths(context.Background())
+			costs := make([]int, len(bestStepDisjointPaths))
+			for i, path := range bestStepDisjointPaths {
+				costs[i] = len(path) - 1
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ComputeAntsPerPath(costs, ants)
+			}
+		})
+	}
+}
+
+func BenchmarkSimulate(b *testing.B) {
+	for _, farm := range benchFarms(b) {
+		b.Run(farm.name, func(b *testing.B) {
+			prepareFarm(b, farm)
+			FindAllPaths(context.Background())
+			FindBestPaths(context.Background())
+			paths, antsPerPath := planPaths(bestStepDisjointPaths)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				simulateAnts(paths, antsPerPath)
+			}
+		})
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	for _, farm := range benchFarms(b) {
+		b.Run(farm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Solve(context.Background(), bytes.NewReader(farm.data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sort"
+)
+
+// BottleneckReport tells where a farm is narrowest and which new tunnels
+// would speed it up the most for its ants.
+type BottleneckReport struct {
+	Ants        int          `json:"ants"`
+	Turns       int          `json:"turns"`    // estimated turns of the farm as it is
+	MaxFlow     int          `json:"max_flow"` // room-disjoint paths, the size of the cut
+	CutRooms    []string     `json:"cut_rooms"`
+	CutTunnels  []Tunnel     `json:"cut_tunnels"`
+	Tried       int          `json:"tried"`       // candidate tunnels evaluated
+	Suggestions []Suggestion `json:"suggestions"` // tunnels saving turns, best first
+}
+
+// Suggestion is a new two-way tunnel and what it would bring.
+type Suggestion struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Turns   int    `json:"turns"` // estimated turns with the tunnel
+	Saved   int    `json:"saved"`
+	MaxFlow int    `json:"max_flow"`
+}
+
+// Bottleneck parses a farm, finds its minimum cut and tries up to limit new
+// tunnels. The turns are estimated from the paths of the flow strategy, not
+// simulated, so the farm and every candidate are compared the same way.
+func Bottleneck(ctx context.Context, r io.Reader, limit int) (*BottleneckReport, error) {
+	if err := lockSolver(ctx); err != nil {
+		return nil, err
+	}
+	defer unlockSolver()
+
+	resetFarm()
+	if err := parseFarm(r); err != nil {
+		return nil, err
+	}
+	if err := checkConnectivity(); err != nil {
+		return nil, err
+	}
+	BuildGraph()
+	return bottleneck(ctx, limit), nil
+}
+
+func bottleneck(ctx context.Context, limit int) *BottleneckReport {
+	report := &BottleneckReport{Ants: ants, Turns: estimateGroupTurns(flowStrategy(ctx))}
+
+	net := newFlowNetwork(graph)
+	net.widenTunnels(graph)
+	for net.augment() {
+		report.MaxFlow++
+	}
+	report.CutRooms, report.CutTunnels = net.minCut(graph)
+	sourceSide := net.residualReach()
+
+	base := graph
+	defer func() { graph = base }()
+	for _, c := range tunnelCandidates(sourceSide, limit) {
+		if expired(ctx) {
+			break
+		}
+		report.Tried++
+
+		graph = base.withTunnel(c[0], c[1])
+		turns := estimateGroupTurns(flowStrategy(ctx))
+		if turns >= report.Turns || expired(ctx) {
+			continue
+		}
+		widened := newFlowNetwork(graph)
+		widened.widenTunnels(graph)
+		flow := 0
+		for widened.augment() {
+			flow++
+		}
+		report.Suggestions = append(report.Suggestions, Suggestion{
+			From:    base.Names[c[0]],
+			To:      base.Names[c[1]],
+			Turns:   turns,
+			Saved:   report.Turns - turns,
+			MaxFlow: flow,
+		})
+	}
+
+	sort.SliceStable(report.Suggestions, func(i, j int) bool {
+		return report.Suggestions[i].Saved > report.Suggestions[j].Saved
+	})
+	return report
+}
+
+// tunnelCandidates picks the limit tunnels most likely to help, as room
+// pairs. New tunnels pay off when they lead from near a start room to near an
+// end room, so the pairs are drawn from the limit rooms closest to a start
+// room and the limit rooms closest to an end room. Tunnels crossing the
+// minimum cut come first, they are the only ones adding a path; then the
+// shortest way through the tunnel.
+func tunnelCandidates(sourceSide []bool, limit int) [][2]int {
+	fromStart := graph.distances(graph.Starts, graph.IsEnd)
+	toEnd := graph.distancesTo(graph.Ends, graph.IsStart)
+	nearest := func(dist []int) []int {
+		var ids []int
+		for id, d := range dist {
+			if d != -1 {
+				ids = append(ids, id)
+			}
+		}
+		sort.SliceStable(ids, func(i, j int) bool { return dist[ids[i]] < dist[ids[j]] })
+		return ids[:min(limit, len(ids))]
+	}
+
+	linked := make(map[[2]int]bool)
+	for id := range graph.Names {
+		for _, neighbor := range graph.Neighbors(id) {
+			linked[[2]int{id, neighbor}] = true
+			linked[[2]int{neighbor, id}] = true
+		}
+	}
+
+	// the tunnel is two-way, ants may take it either way
+	through := func(a, b int) int {
+		length := fromStart[a] + 1 + toEnd[b]
+		if fromStart[b] != -1 && toEnd[a] != -1 {
+			length = min(length, fromStart[b]+1+toEnd[a])
+		}
+		return length
+	}
+
+	type candidate struct {
+		pair     [2]int
+		crossing bool
+		length   int // moves of the shortest way through the tunnel
+	}
+	var candidates []candidate
+	seen := make(map[[2]int]bool)
+	for _, a := range nearest(fromStart) {
+		for _, b := range nearest(toEnd) {
+			pair := [2]int{min(a, b), max(a, b)}
+			if a == b || linked[pair] || seen[pair] || graph.IsStart[a] && graph.IsStart[b] || graph.IsEnd[a] && graph.IsEnd[b] {
+				continue
+			}
+			seen[pair] = true
+			candidates = append(candidates, candidate{
+				pair:     pair,
+				crossing: sourceSide[2*a] != sourceSide[2*b],
+				length:   through(a, b),
+			})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].crossing != candidates[j].crossing {
+			return candidates[i].crossing
+		}
+		return candidates[i].length < candidates[j].length
+	})
+
+	var pairs [][2]int
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		pairs = append(pairs, c.pair)
+	}
+	return pairs
+}
@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Check parses a farm and replays a schedule on it, one line of moves per
+// turn as the solver prints them. It returns the first rule the schedule
+// breaks, or nil when every ant reaches an end room legally.
+func Check(farm io.Reader, moves []string) error {
+	return CheckContext(context.Background(), farm, moves)
+}
+
+// CheckContext is Check giving up with ctx.Err() when ctx ends before the farm
+// can be read.
+func CheckContext(ctx context.Context, farm io.Reader, moves []string) error {
+	if err := lockSolver(ctx); err != nil {
+		return err
+	}
+	defer unlockSolver()
+
+	resetFarm()
+	if err := parseFarm(farm); err != nil {
+		return err
+	}
+	return checkMoves(moves)
+}
+
+// checkMoves replays moves against the parsed farm. The rules are:
+//   - an ant leaves a start room and then only moves through tunnels, one-way
+//     tunnels only in their direction,
+//   - no more ants leave a start room than its "##start <ants>" count,
+//   - ants leave in the order of their numbers, ant n only once the release
+//     schedule made n ants available,
+//   - an ant moves at most once per turn and never after reaching an end room,
+//   - a tunnel is used at most once per turn,
+//   - at the end of a turn a room other than the start and end rooms holds at
+//     most one ant,
+//   - every ant reaches an end room.
+func checkMoves(moves []string) error {
+	position := make(map[int]string) // ants that left their start room
+	spawns := assignSpawns(moves)
+	arrived := 0
+
+	for turn, line := range moves {
+		turn++
+		movedThisTurn := make(map[int]bool)
+		usedTunnels := make(map[[2]string]bool)
+
+		for _, token := range strings.Fields(line) {
+			ant, room, err := parseMoveToken(token)
+			if err != nil {
+				return fmt.Errorf("turn %d: %v", turn, err)
+			}
+			if ant < 1 || ant > ants {
+				return fmt.Errorf("turn %d: unknown ant %d", turn, ant)
+			}
+			if _, ok := rooms[room]; !ok {
+				return fmt.Errorf("turn %d: ant %d moves to unknown room %s", turn, ant, room)
+			}
+			if movedThisTurn[ant] {
+				return fmt.Errorf("turn %d: ant %d moves twice", turn, ant)
+			}
+			movedThisTurn[ant] = true
+
+			from, ok := position[ant]
+			if !ok && ant > releasedBy(turn) {
+				return fmt.Errorf("turn %d: ant %d moves before it is released", turn, ant)
+			}
+			if !ok {
+				if from, ok = spawns[ant]; !ok {
+					return fmt.Errorf("turn %d: ant %d enters %s from no start room with ants left", turn, ant, room)
+				}
+			}
+			if isEndRoom(from) {
+				return fmt.Errorf("turn %d: ant %d moves after reaching the end", turn, ant)
+			}
+			if !hasTunnel(from, room) {
+				return fmt.Errorf("turn %d: ant %d moves from %s to %s without a tunnel", turn, ant, from, room)
+			}
+
+			tunnel := [2]string{from, room}
+			if room < from && !oneWay[tunnel] {
+				tunnel = [2]string{room, from} // both directions are the same tunnel
+			}
+			if usedTunnels[tunnel] {
+				return fmt.Errorf("turn %d: tunnel %s-%s used twice", turn, tunnel[0], tunnel[1])
+			}
+			usedTunnels[tunnel] = true
+
+			position[ant] = room
+			if isEndRoom(room) {
+				arrived++
+			}
+		}
+
+		// rooms are only compared once every ant of the turn has moved
+		holder := make(map[string]int)
+		for ant, room := range position {
+			if isStartRoom(room) || isEndRoom(room) {
+				continue
+			}
+			if other, taken := holder[room]; taken {
+				return fmt.Errorf("turn %d: ants %d and %d are both in %s", turn, min(ant, other), max(ant, other), room)
+			}
+			holder[room] = ant
+		}
+	}
+
+	if arrived != ants {
+		target := endRoom
+		if len(endRooms) > 1 {
+			target = "an end room"
+		}
+		return fmt.Errorf("only %d of %d ants reached %s", arrived, ants, target)
+	}
+	return checkGoals(spawns, position)
+}
+
+// checkGoals compares where the ants went with the #group lines. Groups that
+// name their start room are served first, the others take any ants left that
+// reached their end room.
+func checkGoals(spawns, position map[int]string) error {
+	arrivals := make(map[[2]string]int) // ants by start and end room
+	for ant, room := range position {
+		arrivals[[2]string{spawns[ant], room}]++
+	}
+
+	var anyStart []antGroup
+	for _, group := range antGroups {
+		if group.end == "" {
+			return nil // the ants may go to any end room
+		}
+		if group.start == "" {
+			anyStart = append(anyStart, group)
+			continue
+		}
+		pair := [2]string{group.start, group.end}
+		if arrivals[pair] < group.ants {
+			return fmt.Errorf("only %d of %d ants from %s reached %s", arrivals[pair], group.ants, group.start, group.end)
+		}
+		arrivals[pair] -= group.ants
+	}
+
+	for _, group := range anyStart {
+		got := 0
+		for _, start := range startRooms {
+			pair := [2]string{start, group.end}
+			take := min(arrivals[pair], group.ants-got)
+			arrivals[pair] -= take
+			got += take
+		}
+		if got < group.ants {
+			return fmt.Errorf("only %d of %d ants reached %s", got, group.ants, group.end)
+		}
+	}
+	return nil
+}
+
+// spawnSlot is one use of the tunnel from a start room to room on a turn.
+type spawnSlot struct {
+	turn  int
+	start string
+	room  string
+}
+
+// assignSpawns works out the start room every ant leaves from. Moves only name
+// the room an ant enters, so when several start rooms lead there the ants are
+// matched to start rooms with a flow: a start room sends no more ants than its
+// count and uses each of its tunnels once per turn. Ants that cannot be
+// matched are left out.
+func assignSpawns(moves []string) map[int]string {
+	spawns := make(map[int]string)
+	if len(startRooms) == 1 {
+		for ant := 1; ant <= ants; ant++ {
+			spawns[ant] = startRoom
+		}
+		return spawns
+	}
+
+	left := spawnLimits() // ants each start room can still send
+	used := make(map[spawnSlot]bool)
+
+	// ants with a single start room next to their first room take it right
+	// away, the others are matched below
+	var pending []int
+	var options [][]spawnSlot
+	seen := make(map[int]bool)
+	for turn, line := range moves {
+		for _, token := range strings.Fields(line) {
+			ant, room, err := parseMoveToken(token)
+			if err != nil || seen[ant] {
+				continue
+			}
+			seen[ant] = true
+
+			var slots []spawnSlot
+			for _, start := range startRooms {
+				slot := spawnSlot{turn + 1, start, room}
+				if hasTunnel(start, room) && left[start] > 0 && !used[slot] {
+					slots = append(slots, slot)
+				}
+			}
+			switch {
+			case len(slots) == 1:
+				spawns[ant] = slots[0].start
+				left[slots[0].start]--
+				used[slots[0]] = true
+			case len(slots) > 1:
+				pending = append(pending, ant)
+				options = append(options, slots)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return spawns
+	}
+
+	// source -> ant -> slot -> start room -> sink, every unit of flow is an ant
+	slotIDs := make(map[spawnSlot]int)
+	var slotStarts []string
+	for _, slots := range options {
+		for _, slot := range slots {
+			if _, ok := slotIDs[slot]; !ok {
+				slotIDs[slot] = len(slotStarts)
+				slotStarts = append(slotStarts, slot.start)
+			}
+		}
+	}
+	firstSlot := 2 + len(pending)
+	firstStart := firstSlot + len(slotStarts)
+	net := &flowNetwork{
+		adj:    make([][]flowEdge, firstStart+len(startRooms)),
+		source: 0,
+		sink:   1,
+	}
+	for i, slots := range options {
+		net.addEdge(net.source, 2+i, 1)
+		for _, slot := range slots {
+			net.addEdge(2+i, firstSlot+slotIDs[slot], 1)
+		}
+	}
+	for i, start := range startRooms {
+		for id, slotStart := range slotStarts {
+			if slotStart == start {
+				net.addEdge(firstSlot+id, firstStart+i, 1)
+			}
+		}
+		net.addEdge(firstStart+i, net.sink, left[start])
+	}
+	for net.augment() {
+	}
+
+	for i, ant := range pending {
+		for _, e := range net.adj[2+i] {
+			if e.orig > 0 && e.cap == 0 {
+				spawns[ant] = slotStarts[e.to-firstSlot]
+			}
+		}
+	}
+	return spawns
+}
+
+// spawnLimits returns how many ants each start room sends, from the
+// "##start <ants>" counts or from #group lines that all name their start room.
+// Start rooms without a limit get the number of ants.
+func spawnLimits() map[string]int {
+	limits := make(map[string]int)
+	for _, start := range startRooms {
+		limits[start] = ants
+		if n, ok := startAnts[start]; ok {
+			limits[start] = n
+		}
+	}
+
+	perStart := make(map[string]int)
+	for _, group := range antGroups {
+		if group.start == "" {
+			return limits
+		}
+		perStart[group.start] += group.ants
+	}
+	for _, start := range startRooms {
+		limits[start] = perStart[start]
+	}
+	return limits
+}
+
+// parseMoveToken splits "L<ant>-<room>" into its parts.
+func parseMoveToken(token string) (int, string, error) {
+	id, room, ok := strings.Cut(strings.TrimPrefix(token, "L"), "-")
+	ant, err := strconv.Atoi(id)
+	if !strings.HasPrefix(token, "L") || !ok || err != nil || room == "" {
+		return 0, "", fmt.Errorf("malformed move %q", token)
+	}
+	return ant, room, nil
+}
+
+// hasTunnel reports whether an ant can go from a to b, following the
+// direction of one-way tunnels.
+func hasTunnel(a, b string) bool {
+	for _, neighbor := range tunnels[a] {
+		if neighbor == b {
+			return true
+		}
+	}
+	return false
+}
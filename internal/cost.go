@@ -15,8 +15,7 @@ func capacityForTurn(costs []int, T int) int {
 // computeAntsPerPath allocates exactly totalAnts across paths with costs L_i
 // so that they all finish in the minimum T turns.
 func ComputeAntsPerPath(costs []int, totalAnts int) []int {
-	k := len(costs)
-	antsPerPath := make([]int, k)
+	antsPerPath := make([]int, len(costs))
 
 	// Find minimal T by binary‐search
 	minL, maxL := costs[0], costs[0]
@@ -51,13 +50,155 @@ func ComputeAntsPerPath(costs []int, totalAnts int) []int {
 		sum += cap
 	}
 
-	// If we allocated too many, remove the excess from the longest paths
-	excess := sum - totalAnts
-	for i := k - 1; excess > 0 && i >= 0; i-- {
-		if antsPerPath[i] > 0 {
-			antsPerPath[i]--
-			excess--
+	trimExcess(costs, antsPerPath, sum-totalAnts)
+	return antsPerPath
+}
+
+// trimExcess takes back the ants allocated too many. By default one comes off
+// each of the longest paths. With --objective the ant taken is the one costing
+// the most: the one walking the longest path for "moves", the one arriving
+// last for "arrivals". The turn count stays the same either way.
+func trimExcess(costs, antsPerPath []int, excess int) {
+	if objective == "" {
+		for i := len(antsPerPath) - 1; excess > 0 && i >= 0; i-- {
+			if antsPerPath[i] > 0 {
+				antsPerPath[i]--
+				excess--
+			}
+		}
+		return
+	}
+
+	// cost of the last ant sent through path i
+	last := func(i int) int {
+		if objective == "arrivals" {
+			return costs[i] + antsPerPath[i] - 1
+		}
+		return costs[i]
+	}
+	for ; excess > 0; excess-- {
+		worst := -1
+		for i, n := range antsPerPath {
+			if n > 0 && (worst == -1 || last(i) >= last(worst)) {
+				worst = i
+			}
+		}
+		antsPerPath[worst]--
+	}
+}
+
+// releaseTurn is the smallest T (in the convention of capacityForTurn) by
+// which ants released in batches can all finish on paths with costs L_i. Ants
+// of a batch released at turn r only have T-r turns left, so every batch and
+// the ones after it have to fit into capacityForTurn(costs, T-r).
+func releaseTurn(costs []int, batches []release) int {
+	minL := costs[0]
+	for _, L := range costs {
+		minL = min(minL, L)
+	}
+	total := 0
+	for _, batch := range batches {
+		total += batch.ants
+	}
+
+	fits := func(T int) bool {
+		later := total
+		for _, batch := range batches {
+			if capacityForTurn(costs, T-batch.turn) < later {
+				return false
+			}
+			later -= batch.ants
+		}
+		return true
+	}
+
+	lo, hi := minL+1, batches[len(batches)-1].turn+minL+total
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if fits(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
+
+// computeReleasedAntsPerPath is ComputeAntsPerPath for ants released in
+// batches. It finds the smallest T with releaseTurn and then hands out the
+// ants turn by turn as they become available, shortest path first, to every
+// path that still gets them in by T.
+func computeReleasedAntsPerPath(costs []int, batches []release) []int {
+	T := releaseTurn(costs, batches)
+	antsPerPath := make([]int, len(costs))
+
+	next, waiting := 0, 0
+	for turn := 1; turn < T; turn++ {
+		for ; next < len(batches) && batches[next].turn < turn; next++ {
+			waiting += batches[next].ants
+		}
+		for i, L := range costs {
+			if waiting > 0 && turn <= T-L {
+				antsPerPath[i]++
+				waiting--
+			}
 		}
 	}
 	return antsPerPath
 }
+
+// estimateTurns returns how many turns the ants need on paths (sorted from the
+// shortest), using the same quotas as ComputeAntsPerPath.
+func estimateTurns(paths [][]int, totalAnts int) int {
+	costs := make([]int, len(paths))
+	for i, p := range paths {
+		costs[i] = len(p) - 1
+	}
+	if releases != nil && len(antGroups) == 1 {
+		return releaseTurn(costs, releases) - 1
+	}
+
+	turns := 0
+	for i, n := range ComputeAntsPerPath(costs, totalAnts) {
+		if n > 0 && costs[i]+n-1 > turns {
+			turns = costs[i] + n - 1
+		}
+	}
+	return turns
+}
+
+// estimateGroupTurns is estimateTurns for the ant groups, each group only
+// using the paths of its start room.
+func estimateGroupTurns(paths [][]int) int {
+	turns := 0
+	for _, group := range antGroups {
+		turns = max(turns, estimateTurns(groupPaths(paths, group), group.ants))
+	}
+	return turns
+}
+
+// lowerBound returns a turn count no schedule can beat. At most c ants cross a
+// minimum cut of c rooms per turn, and the first one needs the length of the
+// shortest path to arrive. With a release schedule the same holds for every
+// batch and the ones after it, counted from the turn it is released.
+func lowerBound() int {
+	net := newFlowNetwork(graph)
+	c := 0
+	for net.augment() {
+		c++
+	}
+	if c == 0 {
+		return 0
+	}
+
+	batches := releases
+	if batches == nil {
+		batches = []release{{ants: ants, turn: 0}}
+	}
+	bound, later := 0, ants
+	for _, batch := range batches {
+		bound = max(bound, batch.turn+len(shortestPath)-2+(later+c-1)/c)
+		later -= batch.ants
+	}
+	return bound
+}
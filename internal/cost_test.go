@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestObjective checks which ants the objectives take back. 9 ants on paths
+// of 1, 2, 2 and 3 moves need 5 turns, which fit 12 ants: 3 are too many.
+func TestObjective(t *testing.T) {
+	defer func() { objective = "" }()
+	costs := []int{1, 2, 2, 3}
+
+	for _, tc := range []struct {
+		objective string
+		want      []int
+	}{
+		{"", []int{4, 2, 2, 1}},
+		{"arrivals", []int{4, 2, 2, 1}}, // every last ant arrives on turn 4, so any will do
+		{"moves", []int{4, 3, 2, 0}},
+	} {
+		objective = tc.objective
+		if got := ComputeAntsPerPath(costs, 9); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("objective %q: got %v, want %v", tc.objective, got, tc.want)
+		}
+	}
+}
+
+// TestObjectiveSchedule solves a farm with both objectives: the turns never
+// change and each objective does at least as well as the other on its metric.
+func TestObjectiveSchedule(t *testing.T) {
+	defer func() { objective = "" }()
+	file := filepath.Join("..", "tests", "test2.txt")
+
+	solve := func(o string) *Result {
+		objective = o
+		result, err := SolveFile(context.Background(), file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkMoves(result.Moves); err != nil {
+			t.Fatalf("objective %q: %v", o, err)
+		}
+		return result
+	}
+	byMoves, byArrivals := solve("moves"), solve("arrivals")
+	if byMoves.Turns != byArrivals.Turns {
+		t.Errorf("the objectives changed the turns: %d and %d", byMoves.Turns, byArrivals.Turns)
+	}
+	if byMoves.TotalMoves > byArrivals.TotalMoves || byArrivals.ArrivalSum > byMoves.ArrivalSum {
+		t.Errorf("moves objective: %d moves, %d arrivals; arrivals objective: %d moves, %d arrivals",
+			byMoves.TotalMoves, byMoves.ArrivalSum, byArrivals.TotalMoves, byArrivals.ArrivalSum)
+	}
+}
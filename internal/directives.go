@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Directive handles a "##name args" line, it gets the words after the name.
+// It runs when the line is read; the Annotate it returns, nil when there is
+// nothing to annotate, is applied to the next room or tunnel of the file.
+type Directive func(args []string) (Annotate, error)
+
+// Annotate sets what a directive says about the room or tunnel after it.
+type Annotate func(target *Annotated) error
+
+// Annotated is the room or the tunnel a directive applies to.
+type Annotated struct {
+	Room     string            // the room, "" for a tunnel
+	From, To string            // the tunnel, as written
+	Meta     map[string]string // kept on the room or tunnel and exported with the farm
+}
+
+var directives = make(map[string]Directive)
+
+// annotations waiting for the next room or tunnel line
+type pendingAnnotation struct {
+	directive string
+	annotate  Annotate
+}
+
+var pendingAnnotations []pendingAnnotation
+
+// metadata of the tunnels, set by the directives, see tunnelKey
+var tunnelMeta = make(map[[2]string]map[string]string)
+
+// RegisterDirective makes "##name" lines call d. Registering an existing name
+// replaces it, start and end included.
+func RegisterDirective(name string, d Directive) {
+	directives[name] = d
+}
+
+// MetaDirective is a directive storing its arguments under key in the
+// metadata of the next room or tunnel, e.g. "##label main hall".
+func MetaDirective(key string) Directive {
+	return func(args []string) (Annotate, error) {
+		if len(args) == 0 {
+			return nil, errors.New("missing value for ##" + key)
+		}
+		value := strings.Join(args, " ")
+		return func(target *Annotated) error {
+			target.Meta[key] = value
+			return nil
+		}, nil
+	}
+}
+
+// Besides start and end, the built-in directives only store metadata for the
+// exporters: the solver does not read capacity or weight, a directive acting on
+// them has to be registered in their place.
+func init() {
+	RegisterDirective("start", startDirective)
+	RegisterDirective("end", endDirective)
+	RegisterDirective("label", MetaDirective("label"))
+	RegisterDirective("colour", MetaDirective("colour"))
+	RegisterDirective("capacity", MetaDirective("capacity"))
+	RegisterDirective("weight", MetaDirective("weight"))
+}
+
+// "##start <ants>" also says how many ants spawn in the next room
+func startDirective(args []string) (Annotate, error) {
+	expectingStartRoom = true
+	pendingStartAnts = 0
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 || len(args) > 1 {
+			return nil, errors.New("invalid ant count for start room: " + strings.Join(append([]string{"##start"}, args...), " "))
+		}
+		pendingStartAnts = n
+	}
+	return nil, nil
+}
+
+func endDirective(args []string) (Annotate, error) {
+	expectingEndRoom = true
+	return nil, nil
+}
+
+// runDirective dispatches a "##name args" line. Unknown directives are
+// comments, with a warning.
+func runDirective(line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, "##"))
+	if len(fields) == 0 {
+		return nil // a bare "##" is a comment
+	}
+	d, ok := directives[fields[0]]
+	if !ok {
+		warning := "unknown directive ##" + fields[0] + " treated as a comment"
+		warnings = append(warnings, warning)
+		Log(warning, "warning")
+		return nil
+	}
+	annotate, err := d(fields[1:])
+	if err != nil {
+		return err
+	}
+	if annotate != nil {
+		pendingAnnotations = append(pendingAnnotations, pendingAnnotation{fields[0], annotate})
+	}
+	return nil
+}
+
+// annotateRoom applies the pending directives to a room just created.
+func annotateRoom(name string) error {
+	if len(pendingAnnotations) == 0 {
+		return nil
+	}
+	room := rooms[name]
+	target := &Annotated{Room: name, Meta: room.Meta}
+	if target.Meta == nil {
+		target.Meta = make(map[string]string)
+	}
+	if err := applyAnnotations(target); err != nil {
+		return err
+	}
+	if len(target.Meta) > 0 {
+		room.Meta = target.Meta
+		rooms[name] = room
+	}
+	return nil
+}
+
+// annotateTunnel applies the pending directives to a tunnel just created.
+func annotateTunnel(from, to string) error {
+	if len(pendingAnnotations) == 0 {
+		return nil
+	}
+	key := tunnelKey(from, to)
+	target := &Annotated{From: from, To: to, Meta: tunnelMeta[key]}
+	if target.Meta == nil {
+		target.Meta = make(map[string]string)
+	}
+	if err := applyAnnotations(target); err != nil {
+		return err
+	}
+	if len(target.Meta) > 0 {
+		tunnelMeta[key] = target.Meta
+	}
+	return nil
+}
+
+func applyAnnotations(target *Annotated) error {
+	pending := pendingAnnotations
+	pendingAnnotations = nil
+	for _, p := range pending {
+		if err := p.annotate(target); err != nil {
+			return errors.New("##" + p.directive + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// tunnelKey is how tunnelMeta stores a tunnel: two-way tunnels in name order,
+// the way tunnelList lists them.
+func tunnelKey(from, to string) [2]string {
+	if !oneWay[[2]string{from, to}] && to < from {
+		return [2]string{to, from}
+	}
+	return [2]string{from, to}
+}
+
+// checkDirectives runs once the file is read, directives left without a
+// room or a tunnel to annotate get a warning, an error with --strict.
+func checkDirectives() error {
+	for _, p := range pendingAnnotations {
+		warning := "##" + p.directive + " is not followed by a room or a tunnel"
+		if strict {
+			return errors.New(warning)
+		}
+		warnings = append(warnings, warning)
+		Log(warning, "warning")
+	}
+	pendingAnnotations = nil
+	return nil
+}
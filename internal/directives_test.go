@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const annotatedFarm = `2
+##label entrance
+##start
+s 0 0
+#a comment does not take the label
+##colour blue
+a 1 0
+##end
+e 2 0
+##label main hall
+s-a
+##colour red
+e>s
+##weight 3
+a-e
+##foo 3
+`
+
+func TestDirectives(t *testing.T) {
+	farm, err := Validate(strings.NewReader(annotatedFarm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := make(map[string]map[string]string)
+	for _, room := range farm.Rooms {
+		meta[room.Name] = room.Meta
+	}
+	for _, tunnel := range farm.Tunnels {
+		meta[tunnel.From+"-"+tunnel.To] = tunnel.Meta
+	}
+	want := map[string]map[string]string{
+		"s":   {"label": "entrance"},
+		"a":   {"colour": "blue"},
+		"e":   nil,
+		"a-e": {"weight": "3"},
+		"a-s": {"label": "main hall"}, // two-way tunnels are listed in name order
+		"e-s": {"colour": "red"},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("got metadata %v, want %v", meta, want)
+	}
+	if !reflect.DeepEqual(farm.Starts, []string{"s"}) || !reflect.DeepEqual(farm.Ends, []string{"e"}) {
+		t.Errorf("got starts %v and ends %v", farm.Starts, farm.Ends)
+	}
+	wantWarnings := []string{"unknown directive ##foo treated as a comment"}
+	if !reflect.DeepEqual(farm.Warnings, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", farm.Warnings, wantWarnings)
+	}
+
+	strict = true
+	defer func() { strict = false }()
+	_, err = Validate(strings.NewReader(annotatedFarm))
+	if err == nil || err.Error() != "line 16: unknown command: ##foo" {
+		t.Errorf("--strict: got %v", err)
+	}
+}
+
+// TestRegisterDirective replaces ##capacity by a directive refusing tunnels,
+// without touching the parser.
+func TestRegisterDirective(t *testing.T) {
+	RegisterDirective("capacity", func(args []string) (Annotate, error) {
+		if len(args) != 1 {
+			return nil, errors.New("usage: ##capacity <ants>")
+		}
+		return func(target *Annotated) error {
+			if target.Room == "" {
+				return errors.New("only rooms have a capacity")
+			}
+			target.Meta["capacity"] = args[0]
+			return nil
+		}, nil
+	})
+	defer RegisterDirective("capacity", MetaDirective("capacity"))
+
+	for _, tc := range []struct {
+		farm, want string
+	}{
+		{"1\n##start\ns 0 0\n##capacity 2\na 1 0\n##end\ne 2 0\ns-a\na-e\n", ""},
+		{"1\n##start\ns 0 0\n##capacity\na 1 0\n##end\ne 2 0\ns-a\na-e\n", "line 4: usage: ##capacity <ants>"},
+		{"1\n##start\ns 0 0\na 1 0\n##end\ne 2 0\n##capacity 2\ns-a\na-e\n", "line 8: ##capacity: only rooms have a capacity"},
+	} {
+		farm, err := Validate(strings.NewReader(tc.farm))
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%q: %v", tc.farm, err)
+		case tc.want == "" && farm.Rooms[0].Meta["capacity"] != "2":
+			t.Errorf("%q: got rooms %v", tc.farm, farm.Rooms)
+		case tc.want != "" && (err == nil || err.Error() != tc.want):
+			t.Errorf("%q: got %v, want %q", tc.farm, err, tc.want)
+		}
+	}
+}
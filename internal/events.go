@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// event is one line of an --events script. At the start of turn Turn the
+// tunnel A-B is closed or opened again, or room A is blocked or unblocked.
+// Ants cannot go through a closed tunnel or into a blocked room, an ant
+// already in a blocked room may still leave it.
+//
+//	# turn action tunnel-or-room
+//	3 close a-b
+//	3 block c
+//	6 open a-b
+//	8 unblock c
+type event struct {
+	Line   int // line of the script, for error messages
+	Turn   int
+	Action string // close, open, block or unblock
+	A, B   string // the tunnel A-B, or the room A
+}
+
+// loadEvents reads an event script from a file.
+func loadEvents(filename string) ([]event, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("failed to open events file")
+	}
+	defer file.Close()
+	return parseEvents(file)
+}
+
+// parseEvents reads an event script, sorted by turn. Empty lines and lines
+// starting with # are skipped.
+func parseEvents(r io.Reader) ([]event, error) {
+	var events []event
+	scanner := bufio.NewScanner(r)
+	for numLine := 1; scanner.Scan(); numLine++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ev, err := parseEvent(line)
+		if err != nil {
+			return nil, &LineError{Num: numLine, Line: line, Err: err}
+		}
+		ev.Line = numLine
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Turn < events[j].Turn })
+	return events, nil
+}
+
+func parseEvent(line string) (event, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return event{}, errors.New("want <turn> <close|open|block|unblock> <tunnel or room>")
+	}
+	turn, err := strconv.Atoi(fields[0])
+	if err != nil || turn < 1 {
+		return event{}, errors.New("turn must be a number > 0")
+	}
+
+	ev := event{Turn: turn, Action: fields[1]}
+	switch ev.Action {
+	case "close", "open":
+		a, b, ok := strings.Cut(fields[2], "-")
+		if !ok || a == "" || b == "" {
+			return event{}, errors.New("invalid tunnel: " + fields[2])
+		}
+		ev.A, ev.B = a, b
+	case "block", "unblock":
+		ev.A = fields[2]
+	default:
+		return event{}, errors.New("unknown action: " + ev.Action)
+	}
+	return ev, nil
+}
+
+// checkEvents makes sure the event script only names rooms and tunnels of the
+// parsed farm.
+func checkEvents() error {
+	for _, ev := range eventScript {
+		if _, ok := rooms[ev.A]; !ok {
+			return fmt.Errorf("events line %d: unknown room %s", ev.Line, ev.A)
+		}
+		if ev.B == "" {
+			continue
+		}
+		if _, ok := rooms[ev.B]; !ok {
+			return fmt.Errorf("events line %d: unknown room %s", ev.Line, ev.B)
+		}
+		if !hasTunnel(ev.A, ev.B) && !hasTunnel(ev.B, ev.A) {
+			return fmt.Errorf("events line %d: no tunnel %s-%s", ev.Line, ev.A, ev.B)
+		}
+	}
+	return nil
+}
+
+// failures is what the events did to the farm so far.
+type failures struct {
+	closed  map[[2]int]bool // closed tunnels, in both directions
+	blocked []bool          // by room ID
+}
+
+// apply records ev. It returns false when ev is about a room the pruning
+// removed, nothing changes then.
+func (f *failures) apply(ev event) bool {
+	a, ok := graph.IDs[ev.A]
+	if !ok {
+		return false
+	}
+	b, ok := graph.IDs[ev.B]
+	if ev.B != "" && !ok {
+		return false
+	}
+
+	switch ev.Action {
+	case "close":
+		f.closed[[2]int{a, b}], f.closed[[2]int{b, a}] = true, true
+	case "open":
+		delete(f.closed, [2]int{a, b})
+		delete(f.closed, [2]int{b, a})
+	case "block":
+		f.blocked[a] = true
+	case "unblock":
+		f.blocked[a] = false
+	}
+	return true
+}
+
+// allows reports whether an ant can still follow route.
+func (f *failures) allows(route []int) bool {
+	for i := 1; i < len(route); i++ {
+		if f.blocked[route[i]] || f.closed[[2]int{route[i-1], route[i]}] {
+			return false
+		}
+	}
+	return true
+}
+
+// simulateEvents runs the simulation of paths again with the event script
+// applied. Paths and quotas start out as planned. Whenever events fire, the
+// ants in transit whose way is cut get the shortest way to their goal that is
+// left, or wait where they are when there is none, and the ants that have not
+// spawned yet are planned again on what is left of the farm.
+func simulateEvents(ctx context.Context, paths [][]int) [][]antMove {
+	left := groupSizes()
+	plan, quota, planGroups := planLeft(paths, left)
+	spawned := make([]int, len(plan))
+
+	f := &failures{closed: make(map[[2]int]bool), blocked: make([]bool, len(graph.Names))}
+	next := 0 // the next event to fire
+
+	// every ant gets its own route, Ant.Path indexes routes
+	var routes [][]int
+	var routeGroups []int
+	antsInTransit := []Ant{}
+	occupied := make([]bool, len(graph.Names))
+	nextAnt := 1
+	turns := [][]antMove{}
+
+	for {
+		turn := len(turns) + 1
+
+		fired := false
+		for ; next < len(eventScript) && eventScript[next].Turn <= turn; next++ {
+			if f.apply(eventScript[next]) {
+				fired = true
+			}
+		}
+		if fired {
+			reduced := graph.without(f.closed, f.blocked)
+
+			rerouted := 0
+			for k, ant := range antsInTransit {
+				route := routes[ant.Path]
+				if ant.Index+1 < len(route) && f.allows(route[ant.Index:]) {
+					continue
+				}
+
+				goals := []int{ant.Goal}
+				if antGroups[routeGroups[ant.Path]].end == "" {
+					goals = graph.Ends
+				}
+				current := route[ant.Index]
+				newRoute := reduced.shortestPath([]int{current}, goals)
+				if newRoute == nil {
+					newRoute = []int{current} // wait for something to open again
+				} else {
+					antsInTransit[k].Goal = newRoute[len(newRoute)-1]
+				}
+				routes[ant.Path] = newRoute
+				antsInTransit[k].Index = 0
+				rerouted++
+			}
+
+			waiting := 0
+			for _, n := range left {
+				waiting += n
+			}
+			if waiting > 0 {
+				withGraph(reduced, func() {
+					plan, quota, planGroups = planLeft(flowStrategy(ctx), left)
+				})
+				spawned = make([]int, len(plan))
+			}
+			Log(fmt.Sprintf("turn %d: re-planned %d ants in transit and %d ants waiting to spawn", turn, rerouted, waiting), "info")
+		}
+
+		turnOutput := []antMove{}
+
+		var moves []antMove
+		antsInTransit, moves = moveAntsInTransit(antsInTransit, routes, occupied)
+		turnOutput = append(turnOutput, moves...)
+
+		released := releasedBy(turn)
+		for i, path := range plan {
+			room := path[1]
+			goal := path[len(path)-1]
+			if spawned[i] >= quota[i] || nextAnt > released || (room != goal && occupied[room]) {
+				continue
+			}
+
+			spawned[i]++
+			left[planGroups[i]]--
+			routes = append(routes, path)
+			routeGroups = append(routeGroups, planGroups[i])
+			antsInTransit = append(antsInTransit, Ant{ID: nextAnt, Path: len(routes) - 1, Index: 1, Goal: goal})
+			turnOutput = append(turnOutput, antMove{Ant: nextAnt, From: path[0], Room: room})
+			if room != goal {
+				occupied[room] = true
+			}
+			nextAnt++
+		}
+
+		if len(turnOutput) == 0 {
+			done := nextAnt > ants && len(antsInTransit) == 0
+			waitingForRelease := nextAnt > released && released < ants
+			if done || (next == len(eventScript) && !waitingForRelease) {
+				break
+			}
+		}
+
+		sort.Slice(turnOutput, func(i, j int) bool {
+			return turnOutput[i].Ant < turnOutput[j].Ant
+		})
+		turns = append(turns, turnOutput)
+	}
+
+	// turns spent waiting for events that did not help are not part of the schedule
+	for len(turns) > 0 && len(turns[len(turns)-1]) == 0 {
+		turns = turns[:len(turns)-1]
+	}
+	return turns
+}
+
+// groupSizes returns the number of ants in every group.
+func groupSizes() []int {
+	sizes := make([]int, len(antGroups))
+	for i, group := range antGroups {
+		sizes[i] = group.ants
+	}
+	return sizes
+}
+
+// withGraph runs fn with g standing in for the farm graph. The planners all
+// read the package graph, this lets them plan on a farm with failures.
+func withGraph(g *Graph, fn func()) {
+	saved := graph
+	graph = g
+	defer func() { graph = saved }()
+	fn()
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		script string
+		err    string
+	}{
+		{"# comment\n\n3 close a-b\n1 block c\n", ""},
+		{"3 close a-b extra\n", "line 1: want <turn> <close|open|block|unblock> <tunnel or room>"},
+		{"0 block c\n", "line 1: turn must be a number > 0"},
+		{"2 close ab\n", "line 1: invalid tunnel: ab"},
+		{"2 explode c\n", "line 1: unknown action: explode"},
+	}
+
+	for _, tt := range tests {
+		events, err := parseEvents(strings.NewReader(tt.script))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.err {
+			t.Errorf("%q: got error %q, want %q", tt.script, got, tt.err)
+		}
+		if err == nil && (len(events) != 2 || events[0].Turn != 1 || events[0].Line != 4) {
+			t.Errorf("%q: events not sorted by turn: %+v", tt.script, events)
+		}
+	}
+}
+
+// TestEventsReplan closes the short lane of a farm for a few turns and checks
+// that no ant uses it meanwhile, that every ant still arrives and that the
+// failures are reported against the schedule without them.
+func TestEventsReplan(t *testing.T) {
+	events, err := parseEvents(strings.NewReader("2 close a-e\n3 block b\n6 open a-e\n6 unblock b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventScript = events
+	defer func() { eventScript = nil }()
+
+	result, err := SolveFile(context.Background(), filepath.Join("..", "tests", "release.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMoves(result.Moves); err != nil {
+		t.Fatalf("schedule breaks the rules: %v", err)
+	}
+	if result.BaselineTurns == 0 || result.Turns < result.BaselineTurns {
+		t.Errorf("got %d turns against a baseline of %d", result.Turns, result.BaselineTurns)
+	}
+
+	position := make(map[int]string)
+	for turn, line := range result.Moves {
+		turn++
+		for _, token := range strings.Fields(line) {
+			ant, room, _ := parseMoveToken(token)
+			if turn >= 2 && turn < 6 && position[ant] == "a" && room == "e" {
+				t.Errorf("turn %d: ant %d goes through the closed tunnel a-e", turn, ant)
+			}
+			if turn >= 3 && turn < 6 && room == "b" {
+				t.Errorf("turn %d: ant %d enters the blocked room b", turn, ant)
+			}
+			position[ant] = room
+		}
+	}
+}
@@ -1,38 +1,130 @@
 package internal
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
-func FindAllPaths() {
-	visited := make(map[string]bool)
-	path := []string{}
-	allPaths = [][]string{} // clear previous results just in case
+// searchShare is the part of the time left in ctx the search may use, the
+// rest is kept for the strategies and the simulation.
+const searchShare = 0.75
 
-	DFS(startRoom, visited, path)
+// FindAllPaths enumerates every path from each start room to an end room.
+// When its share of the time budget runs out the search stops and keeps the
+// paths found so far, along with the BFS shortest path of every start room.
+//
+// --max-paths stops the search after that many paths and --max-path-len skips
+// paths longer than the shortest one plus the given number of steps.
+func FindAllPaths(ctx context.Context) {
+	allPaths = [][]int{} // clear previous results just in case
+
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*searchShare))
+		defer cancel()
+	}
+
+	distToEnd = graph.distancesTo(graph.Ends, graph.IsStart)
+	for _, start := range graph.Starts {
+		stepLimit = -1
+		if maxPathLen >= 0 {
+			stepLimit = distToEnd[start] + maxPathLen
+		}
+		if distToEnd[start] != -1 {
+			DFS(ctx, start)
+		}
+	}
+
+	if maxPaths > 0 && len(allPaths) >= maxPaths {
+		Log(fmt.Sprintf("stopped the search after %d paths (--max-paths)", maxPaths), "info")
+	}
+
+	if expired(ctx) {
+		timedOut = true
+		Log(fmt.Sprintf("time budget of %s ran out after %d paths, the result may be suboptimal", timeout, len(allPaths)), "info")
+	}
+
+	// a start room cut off by --max-paths still gets its shortest path, and
+	// after a timeout the partial search may have missed it, so it is a candidate too
+	for _, path := range shortestPaths {
+		ids := graph.pathIDs(path)
+		if !hasPathFrom(allPaths, ids[0]) || timedOut && !slices.ContainsFunc(allPaths, func(p []int) bool { return slices.Equal(p, ids) }) {
+			allPaths = append(allPaths, ids)
+		}
+	}
 
 	// print how many were found
-	Log(fmt.Sprintf("Found %d valid paths from %s to %s", len(allPaths), startRoom, endRoom), "debug")
+	Log(fmt.Sprintf("Found %d valid paths from %s to %s", len(allPaths), strings.Join(startRooms, ", "), strings.Join(endRooms, ", ")), "debug")
 }
 
-func DFS(current string, visited map[string]bool, path []string) {
-	// Mark current room as visited and add to path
-	visited[current] = true
-	path = append(path, current)
-
-	// If we reached the endRoom, save a copy of the path
-	if current == endRoom {
-		// Make a deep copy of path to avoid mutation
-		pathCopy := make([]string, len(path))
-		copy(pathCopy, path)
-		allPaths = append(allPaths, pathCopy)
+// hasPathFrom reports whether one of paths starts in room start.
+func hasPathFrom(paths [][]int, start int) bool {
+	for _, path := range paths {
+		if path[0] == start {
+			return true
+		}
 	}
+	return false
+}
 
-	// Recurse into neighbors
-	for _, neighbor := range tunnels[current] {
-		if !visited[neighbor] {
-			DFS(neighbor, visited, path)
+// dfsFrame is one room on the DFS stack and the next neighbour to try from it.
+type dfsFrame struct {
+	room int
+	next int // index into the room's neighbours
+}
+
+// DFS walks every simple path from start to an end room with an explicit stack,
+// so long corridors cannot overflow the call stack. Paths are appended to
+// allPaths in the same order a recursive search would find them.
+func DFS(ctx context.Context, start int) {
+	visited := make([]bool, len(graph.Names))
+	path := []int{start}
+	stack := []dfsFrame{{room: start}}
+	visited[start] = true
+
+	for steps := 0; len(stack) > 0; steps++ {
+		// Give up as soon as the time budget is spent or enough paths were found.
+		// expired takes a lock and reads the clock, so only look every few hundred steps.
+		if steps%256 == 0 && expired(ctx) {
+			return
+		}
+		if maxPaths > 0 && len(allPaths) >= maxPaths {
+			return
+		}
+
+		top := &stack[len(stack)-1]
+		neighbors := graph.Neighbors(top.room)
+		if graph.IsEnd[top.room] || top.next == len(neighbors) {
+			// Backtrack: unmark current room
+			visited[top.room] = false
+			stack = stack[:len(stack)-1]
+			path = path[:len(path)-1]
+			continue
 		}
-	}
 
-	// Backtrack: unmark current room
-	visited[current] = false
+		neighbor := neighbors[top.next]
+		top.next++
+		if visited[neighbor] || graph.IsStart[neighbor] {
+			continue
+		}
+
+		// Skip the room when even the shortest way on from it is too long
+		if stepLimit >= 0 && (distToEnd[neighbor] == -1 || len(path)+distToEnd[neighbor] > stepLimit) {
+			continue
+		}
+
+		visited[neighbor] = true
+		path = append(path, neighbor)
+		stack = append(stack, dfsFrame{room: neighbor})
+
+		// If we reached the end, save a copy of the path
+		if graph.IsEnd[neighbor] {
+			pathCopy := make([]int, len(path))
+			copy(pathCopy, path)
+			allPaths = append(allPaths, pathCopy)
+		}
+	}
 }
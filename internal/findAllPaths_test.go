@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// TestFindAllPathsCancelled searches with a context that is already done: the
+// search gives up at once but the BFS shortest path is still there to use.
+func TestFindAllPathsCancelled(t *testing.T) {
+	loadFarm(t, writeLadderFarm(t, 4, 6, 10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	FindAllPaths(ctx)
+
+	if !timedOut {
+		t.Error("timedOut is not set")
+	}
+	if len(allPaths) == 0 {
+		t.Fatal("no path to fall back on")
+	}
+	shortest := graph.pathIDs(shortestPath)
+	if !slices.ContainsFunc(allPaths, func(p []int) bool { return slices.Equal(p, shortest) }) {
+		t.Errorf("the shortest path %v is not among %v", shortestPath, allPaths)
+	}
+}
@@ -1,32 +1,51 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
 
-func FindBestPaths() {
-	maxPaths := len(allPaths)
-
+func FindBestPaths(ctx context.Context) {
 	Log("Evaluating additional disjoint paths (based on steps)...", "debug")
-	sortedPaths := getSortedPathsBySteps()
+	bestStepDisjointPaths = selectStepPaths(ctx, allPaths)
 
 	// Save the best step path (shortest path)
-	bestStepPath = sortedPaths[0]
+	bestStepPath = bestStepDisjointPaths[0]
+
+	// Log the final ordered disjoint paths.
+	for i, path := range bestStepDisjointPaths {
+		Log(fmt.Sprintf("Step Path %d : %v", i+1, graph.pathNames(path)), "debug")
+	}
+}
+
+// selectStepPaths keeps the shortest path plus the disjoint paths that do not
+// share its first intermediate room. It only reads paths, so it is safe to run
+// alongside other strategies. When ctx expires it keeps the paths selected so
+// far, the shortest one alone if there was no time to sort them.
+func selectStepPaths(ctx context.Context, paths [][]int) [][]int {
+	if expired(ctx) {
+		return [][]int{shortestOf(paths)}
+	}
+	maxPaths := len(paths)
+	sortedPaths := getSortedPathsBySteps(paths)
+
+	// The shortest path always goes first
+	bestPath := sortedPaths[0]
 
 	// Remove the first path from the sorted list before selecting disjoint paths
 	remainingPaths := sortedPaths[1:]
 
 	// Select disjoint paths excluding the best path
-	bestStepDisjointPaths = append([][]string{bestStepPath}, selectDisjointPaths(remainingPaths, maxPaths-1)...)
+	disjointPaths := append([][]int{bestPath}, selectDisjointPaths(ctx, remainingPaths, maxPaths-1, 1)...)
 
 	// Ensure that, besides the best path, additional paths have a unique first intermediate room.
-	// In other words, the second, third, etc. paths should not start with the same room as bestStepPath.
-	var uniqueFirstPaths [][]string
-	uniqueFirstPaths = append(uniqueFirstPaths, bestStepPath)
-	for _, path := range bestStepDisjointPaths[1:] {
+	// In other words, the second, third, etc. paths should not start with the same room as bestPath.
+	var uniqueFirstPaths [][]int
+	uniqueFirstPaths = append(uniqueFirstPaths, bestPath)
+	for _, path := range disjointPaths[1:] {
 		// path[1] is the first intermediate room after start.
-		if path[1] != bestStepPath[1] {
+		if path[1] != bestPath[1] {
 			duplicate := false
 			// Also check among already accepted paths to ensure uniqueness.
 			for _, up := range uniqueFirstPaths {
@@ -40,18 +59,24 @@ func FindBestPaths() {
 			}
 		}
 	}
-	bestStepDisjointPaths = uniqueFirstPaths
+	return uniqueFirstPaths
+}
 
-	// Log the final ordered disjoint paths.
-	for i, path := range bestStepDisjointPaths {
-		Log(fmt.Sprintf("Step Path %d : %v", i+1, path), "debug")
+// shortestOf returns the first of the shortest paths, without sorting them.
+func shortestOf(paths [][]int) []int {
+	shortest := paths[0]
+	for _, path := range paths[1:] {
+		if len(path) < len(shortest) {
+			shortest = path
+		}
 	}
+	return shortest
 }
 
-// Sorts allPaths by the number of steps (path length) in ascending order.
-func getSortedPathsBySteps() [][]string {
-	sortedPaths := make([][]string, len(allPaths))
-	copy(sortedPaths, allPaths)
+// Sorts paths by the number of steps (path length) in ascending order.
+func getSortedPathsBySteps(paths [][]int) [][]int {
+	sortedPaths := make([][]int, len(paths))
+	copy(sortedPaths, paths)
 
 	// Sort by length (fewest steps first).
 	sort.Slice(sortedPaths, func(i, j int) bool {
@@ -61,12 +86,18 @@ func getSortedPathsBySteps() [][]string {
 	return sortedPaths
 }
 
-func selectDisjointPaths(paths [][]string, max int) [][]string {
-	selected := [][]string{}
-	used := map[string]int{}
-	threshold := 1 // Set threshold to 0 for strict, or increase it to allow some overlap.
+// selectDisjointPaths greedily accepts paths that share at most threshold
+// intermediate rooms with the ones already accepted.
+// Use threshold 0 for strict, or increase it to allow some overlap.
+// Once a path is accepted, an expired ctx ends the selection.
+func selectDisjointPaths(ctx context.Context, paths [][]int, max int, threshold int) [][]int {
+	selected := [][]int{}
+	used := make([]int, len(graph.Names))
 
-	for _, path := range paths {
+	for i, path := range paths {
+		if i%256 == 0 && len(selected) > 0 && expired(ctx) {
+			break // keep the selection so far
+		}
 		overlap := 0
 		for _, room := range path[1 : len(path)-1] { // Exclude start and end.
 			if used[room] > 0 {
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addFarmSeeds seeds a fuzz corpus with the farms in tests/ and a small
+// generated one.
+func addFarmSeeds(f *testing.F) {
+	f.Helper()
+
+	for _, pattern := range []string{"*.txt", filepath.Join("invalid", "*.txt")} {
+		files, err := filepath.Glob(filepath.Join("..", "tests", pattern))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(string(data))
+		}
+	}
+
+	data, err := os.ReadFile(writeLadderFarm(f, 3, 4, 7))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(string(data))
+}
+
+func FuzzProcessLine(f *testing.F) {
+	for _, line := range []string{"4", "##start", "##end", "room 1 2", "a-b", "#comment", "x 1", "L1 2 3", "a-b-c", " - "} {
+		f.Add(line, 1)
+		f.Add(line, 2)
+	}
+
+	f.Fuzz(func(t *testing.T, line string, numLine int) {
+		resetFarm()
+		rooms["a"] = Room{Name: "a"}
+		rooms["b"] = Room{Name: "b"}
+		processLine(line, numLine) // must not panic, errors are fine
+	})
+}
+
+func FuzzParseFarm(f *testing.F) {
+	addFarmSeeds(f)
+
+	f.Fuzz(func(t *testing.T, farm string) {
+		resetFarm()
+		if err := parseFarm(strings.NewReader(farm)); err != nil {
+			return
+		}
+		if startRoom == "" || endRoom == "" || startRoom == endRoom {
+			t.Fatalf("parsed without error but start is %q and end is %q", startRoom, endRoom)
+		}
+		for room, neighbors := range tunnels {
+			for _, neighbor := range neighbors {
+				if _, ok := rooms[neighbor]; !ok {
+					t.Fatalf("tunnel from %s to unknown room %s", room, neighbor)
+				}
+			}
+		}
+	})
+}
+
+// FuzzSolve checks that every farm either fails with an error or gets a
+// schedule that follows the rules and delivers every ant.
+func FuzzSolve(f *testing.F) {
+	addFarmSeeds(f)
+
+	f.Fuzz(func(t *testing.T, farm string) {
+		// keep single runs short, the number of ants is the number of moves to check
+		first, _, _ := strings.Cut(farm, "\n")
+		if n, err := strconv.Atoi(first); err == nil && n > 200 {
+			t.Skip("too many ants")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		result, err := Solve(ctx, bytes.NewReader([]byte(farm)))
+		if err != nil {
+			if result != nil {
+				t.Fatalf("got a result along with error %v", err)
+			}
+			return
+		}
+		if err := checkMoves(result.Moves); err != nil {
+			t.Fatalf("schedule breaks the rules: %v\nmoves:\n%s", err, strings.Join(result.Moves, "\n"))
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// checkGeometry looks at where the rooms are drawn. The solver does not care,
+// but some specs want unique, non-negative coordinates and a drawing without
+// crossing tunnels. Every finding is a warning, and an error with --strict.
+// Crossings are summed up in one line, the sweep stops after maxCrossings of
+// them, or after the first with --strict.
+func checkGeometry() error {
+	var found []string
+
+	// rooms given without coordinates have none to check
+	names := make([]string, 0, len(rooms))
+	for name, room := range rooms {
+		if !room.Unplaced {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if room := rooms[name]; room.X < 0 || room.Y < 0 {
+			found = append(found, fmt.Sprintf("room %s has negative coordinates %d %d", name, room.X, room.Y))
+		}
+	}
+
+	at := make(map[[2]int][]string)
+	var spots [][2]int
+	for _, name := range names {
+		spot := [2]int{rooms[name].X, rooms[name].Y}
+		if len(at[spot]) == 0 {
+			spots = append(spots, spot)
+		}
+		at[spot] = append(at[spot], name)
+	}
+	for _, spot := range spots {
+		if len(at[spot]) > 1 {
+			found = append(found, fmt.Sprintf("rooms %s share the coordinates %d %d", strings.Join(at[spot], ", "), spot[0], spot[1]))
+		}
+	}
+
+	limit := maxCrossings
+	if strict {
+		limit = 1 // the first one is the error
+	}
+	if crossing := crossingTunnels(limit); len(crossing) > 0 {
+		first := crossing[0]
+		message := fmt.Sprintf("tunnels %s-%s and %s-%s cross", first[0].From, first[0].To, first[1].From, first[1].To)
+		switch {
+		case len(crossing) == limit && limit > 1:
+			message = fmt.Sprintf("at least %d pairs of tunnels cross, the first are %s-%s and %s-%s", limit, first[0].From, first[0].To, first[1].From, first[1].To)
+		case len(crossing) > 1:
+			message = fmt.Sprintf("%d pairs of tunnels cross, the first are %s-%s and %s-%s", len(crossing), first[0].From, first[0].To, first[1].From, first[1].To)
+		}
+		found = append(found, message)
+	}
+
+	if len(found) > 0 && strict {
+		return errors.New(found[0])
+	}
+	warnings = append(warnings, found...)
+	for _, warning := range found {
+		Log(warning, "warning")
+	}
+	return nil
+}
+
+// maxCrossings is where the sweep stops counting, a big farm drawn without
+// care can have millions of crossings.
+const maxCrossings = 1000
+
+// crossingTunnels returns the pairs of tunnels whose segments meet, other
+// than at a room they share, at most limit of them. The tunnels are swept
+// from left to right, only the ones overlapping on x are compared.
+func crossingTunnels(limit int) [][2]Tunnel {
+	type segment struct {
+		tunnel Tunnel
+		a, b   Room // a is the leftmost end
+	}
+	var segments []segment
+	for _, t := range tunnelList() {
+		a, b := rooms[t.From], rooms[t.To]
+		if a.Unplaced || b.Unplaced {
+			continue
+		}
+		if b.X < a.X || b.X == a.X && b.Y < a.Y {
+			a, b = b, a
+		}
+		segments = append(segments, segment{t, a, b})
+	}
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].a.X < segments[j].a.X })
+
+	var crossing [][2]Tunnel
+	for i, s := range segments {
+		for _, o := range segments[i+1:] {
+			if o.a.X > s.b.X {
+				break
+			}
+			if s.a.Name == o.a.Name || s.a.Name == o.b.Name || s.b.Name == o.a.Name || s.b.Name == o.b.Name {
+				continue // tunnels meeting in a room
+			}
+			if segmentsMeet(s.a, s.b, o.a, o.b) {
+				crossing = append(crossing, [2]Tunnel{s.tunnel, o.tunnel})
+				if len(crossing) == limit {
+					return crossing
+				}
+			}
+		}
+	}
+	return crossing
+}
+
+// segmentsMeet tells whether the segments p1-p2 and q1-q2 have a point in
+// common, touching and overlapping included.
+func segmentsMeet(p1, p2, q1, q2 Room) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return d1 == 0 && onSegment(q1, q2, p1) ||
+		d2 == 0 && onSegment(q1, q2, p2) ||
+		d3 == 0 && onSegment(p1, p2, q1) ||
+		d4 == 0 && onSegment(p1, p2, q2)
+}
+
+// orientation is the sign of the turn from a-b to a-c: 1 counterclockwise, -1
+// clockwise, 0 when the three points are aligned.
+func orientation(a, b, c Room) int {
+	cross := int64(b.X-a.X)*int64(c.Y-a.Y) - int64(b.Y-a.Y)*int64(c.X-a.X)
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	}
+	return 0
+}
+
+// onSegment tells whether c, aligned with a and b, lies between them.
+func onSegment(a, b, c Room) bool {
+	return min(a.X, b.X) <= c.X && c.X <= max(a.X, b.X) && min(a.Y, b.Y) <= c.Y && c.Y <= max(a.Y, b.Y)
+}
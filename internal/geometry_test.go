@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the tunnels s-e and a-b cross in the middle, c and d are on the same spot
+const untidyFarm = `2
+##start
+s 0 0
+a 2 -1
+b 2 1
+c 5 5
+d 5 5
+##end
+e 4 0
+s-e
+a-b
+s-c
+c-d
+`
+
+func TestGeometryWarnings(t *testing.T) {
+	farm, err := Validate(strings.NewReader(untidyFarm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"room a has negative coordinates 2 -1",
+		"rooms c, d share the coordinates 5 5",
+		"tunnels e-s and a-b cross",
+	}
+	if !reflect.DeepEqual(farm.Warnings, want) {
+		t.Errorf("got warnings %q, want %q", farm.Warnings, want)
+	}
+
+	// several crossings are one warning
+	farm, err = Validate(strings.NewReader(untidyFarm + "f 4 -2\ng 4 2\nf-g\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := farm.Warnings[len(farm.Warnings)-1]; last != "2 pairs of tunnels cross, the first are e-s and a-b" {
+		t.Errorf("got %q", last)
+	}
+
+	strict = true
+	defer func() { strict = false }()
+	if _, err := Validate(strings.NewReader(untidyFarm)); err == nil || err.Error() != want[0] {
+		t.Errorf("--strict: got %v, want %q", err, want[0])
+	}
+}
+
+func TestSegmentsMeet(t *testing.T) {
+	at := func(x, y int) Room { return Room{X: x, Y: y} }
+	for _, tc := range []struct {
+		p1, p2, q1, q2 Room
+		want           bool
+	}{
+		{at(0, 0), at(4, 4), at(0, 4), at(4, 0), true},  // an X
+		{at(0, 0), at(4, 0), at(0, 1), at(4, 1), false}, // parallel
+		{at(0, 0), at(4, 0), at(2, 0), at(6, 0), true},  // overlapping on one line
+		{at(0, 0), at(2, 0), at(3, 0), at(6, 0), false}, // on one line, apart
+		{at(0, 0), at(4, 0), at(2, 0), at(2, 3), true},  // one ends on the other
+		{at(0, 0), at(4, 0), at(2, 1), at(2, 3), false},
+	} {
+		if got := segmentsMeet(tc.p1, tc.p2, tc.q1, tc.q2); got != tc.want {
+			t.Errorf("%v-%v and %v-%v: got %v", tc.p1, tc.p2, tc.q1, tc.q2, got)
+		}
+	}
+}
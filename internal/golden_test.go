@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the farms in tests/")
+
+// TestGoldenFarms solves every farm in tests/, checks the schedule against the
+// rules and compares it with the <farm>.golden file next to it. One line of the
+// golden file is one turn. Run with -update after an intended change.
+func TestGoldenFarms(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "tests", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".txt")
+		t.Run(name, func(t *testing.T) {
+			result, err := SolveFile(context.Background(), file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := checkMoves(result.Moves); err != nil {
+				t.Fatalf("schedule breaks the rules: %v", err)
+			}
+
+			golden := strings.TrimSuffix(file, ".txt") + ".golden"
+			got := strings.Join(result.Moves, "\n") + "\n"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			wantTurns := strings.Count(string(want), "\n")
+			if result.Turns != wantTurns {
+				t.Errorf("solved in %d turns, want %d", result.Turns, wantTurns)
+			}
+			if got != string(want) {
+				t.Errorf("moves differ from %s:\ngot:\n%swant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestInvalidFarms(t *testing.T) {
+	tests := []struct {
+		file string
+		err  string
+	}{
+		{"bad_ants.txt", "line 1: number of ants must be a digit"},
+		{"zero_ants.txt", "line 1: number of ants must be > 0"},
+		{"no_start.txt", "no start room found."},
+		{"no_end.txt", "no end room found."},
+		{"same_start_end.txt", "line 4: room is both a start and an end room: room"},
+		{"bad_start_count.txt", "line 2: invalid ant count for start room: ##start two"},
+		{"start_counts_sum.txt", "ant counts of the start rooms add up to 4, not 5"},
+		{"missing_start_count.txt", "no ant count for start room: b"},
+		{"start_no_path.txt", "no path from start room b to an end room"},
+		{"group_not_end.txt", "group destination is not an end room: mid"},
+		{"group_sum.txt", "group sizes add up to 2, not 3"},
+		{"group_bad_count.txt", "line 2: invalid ant count for group: #group three e"},
+		{"group_start_counts.txt", "#group lines and ##start ant counts cannot be mixed, name the start room in #group instead"},
+		{"group_no_path.txt", "no path from a start room to end room e2"},
+		{"release_sum.txt", "release schedule releases 2 ants, not 3"},
+		{"release_bad.txt", "line 2: invalid release batch 3@-1, want <ants>@<turn>"},
+		{"release_twice.txt", "line 3: duplicate release schedule: #release 3@1"},
+		{"release_groups.txt", "a release schedule cannot be combined with several ant groups"},
+		{"duplicate_room.txt", "line 4: duplicate room name: start"},
+		{"bad_coordinates.txt", "line 3: invalid coordinates for room: start 0 x"},
+		{"bad_room_name.txt", "line 4: invalid room name: Lroom"},
+		{"unknown_room.txt", "line 6: unknown room in tunnel: x"},
+		{"self_link.txt", "line 6: invalid tunnel: self-link on start"},
+		{"duplicate_tunnel.txt", "line 7: duplicate tunnel between end and start"},
+		{"no_path.txt", "no path from start to end"},
+		{"duplicate_one_way.txt", "line 7: duplicate tunnel between s and e"},
+		{"one_way_no_path.txt", "no path from start to end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSuffix(tt.file, ".txt"), func(t *testing.T) {
+			_, err := SolveFile(context.Background(), filepath.Join("..", "tests", "invalid", tt.file))
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.err)
+			}
+			if err.Error() != tt.err {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckMoves(t *testing.T) {
+	farm := "2\n##start\ns 0 0\na 1 0\nb 1 1\n##end\ne 2 0\ns-a\na-e\ns-b\nb-e\n"
+
+	tests := []checkTest{
+		{"valid", []string{"L1-a L2-b", "L1-e L2-e"}, ""},
+		{"no tunnel", []string{"L1-e"}, "turn 1: ant 1 moves from s to e without a tunnel"},
+		{"shared room", []string{"L1-a", "L2-a"}, "turn 2: ants 1 and 2 are both in a"},
+		{"tunnel twice", []string{"L1-a L2-a"}, "turn 1: tunnel a-s used twice"},
+		{"moves twice", []string{"L1-a L1-e"}, "turn 1: ant 1 moves twice"},
+		{"unknown ant", []string{"L3-a"}, "turn 1: unknown ant 3"},
+		{"unknown room", []string{"L1-x"}, "turn 1: ant 1 moves to unknown room x"},
+		{"malformed", []string{"1-a"}, `turn 1: malformed move "1-a"`},
+		{"follow", []string{"L1-a", "L1-e L2-a", "L2-e"}, ""},
+		{"not delivered", []string{"L1-a L2-b", "L1-e"}, "only 1 of 2 ants reached e"},
+	}
+	runCheckTests(t, farm, tests)
+}
+
+func TestCheckOneWayMoves(t *testing.T) {
+	farm := "2\n##start\ns 0 0\na 1 0\nb 1 1\n##end\ne 2 0\ns>a\na>e\ns-b\nb>a\na>b\n"
+
+	tests := []checkTest{
+		{"valid", []string{"L1-a L2-b", "L1-e L2-a", "L2-e"}, ""},
+		{"against the tunnel", []string{"L1-b", "L1-a", "L1-s"}, "turn 3: ant 1 moves from a to s without a tunnel"},
+		{"both one-way tunnels", []string{"L1-a L2-b", "L1-b L2-a", "L1-a L2-e", "L1-e"}, ""},
+	}
+	runCheckTests(t, farm, tests)
+}
+
+func TestCheckMultiStartMoves(t *testing.T) {
+	farm := "3\n##start 2\ns1 0 0\n##start 1\ns2 0 2\na 1 1\n##end\ne1 2 0\n##end\ne2 2 2\ns1-a\ns2-a\na-e1\na-e2\ns1-e1\n"
+
+	tests := []checkTest{
+		{"valid", []string{"L1-a L2-e1", "L1-e2 L3-a", "L3-e1"}, ""},
+		{"too many from s1", []string{"L1-e1", "L2-e1", "L3-e1"}, "turn 3: ant 3 enters e1 from no start room with ants left"},
+		{"not delivered", []string{"L1-a L2-e1", "L1-e2"}, "only 2 of 3 ants reached an end room"},
+	}
+	runCheckTests(t, farm, tests)
+}
+
+func TestCheckGroupMoves(t *testing.T) {
+	farm := "3\n#group 2 e1\n#group 1 e2\n##start\ns 0 0\n##end\ne1 2 0\n##end\ne2 2 2\ns-e1\ns-e2\n"
+
+	tests := []checkTest{
+		{"valid", []string{"L1-e1 L2-e2", "L3-e1"}, ""},
+		{"wrong end", []string{"L1-e1 L2-e2", "L3-e2"}, "only 1 of 2 ants reached e1"},
+	}
+	runCheckTests(t, farm, tests)
+}
+
+func TestCheckReleasedMoves(t *testing.T) {
+	farm := "3\n#release 1@0 2@2\n##start\ns 0 0\n##end\ne 2 0\ns-e\n"
+
+	tests := []checkTest{
+		{"valid", []string{"L1-e", "", "L2-e", "L3-e"}, ""},
+		{"too early", []string{"L1-e", "L2-e"}, "turn 2: ant 2 moves before it is released"},
+	}
+	runCheckTests(t, farm, tests)
+}
+
+// checkTest is a schedule for Check and the error it should produce.
+type checkTest struct {
+	name  string
+	moves []string
+	err   string
+}
+
+func runCheckTests(t *testing.T, farm string, tests []checkTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(strings.NewReader(farm), tt.moves)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.err {
+				t.Errorf("got error %q, want %q", got, tt.err)
+			}
+		})
+	}
+}
+
+// TestShowSpawns prints tests/multi.txt with --show-spawns: the first move of
+// every ant names the start room Result.Spawns gives, the others are as usual.
+func TestShowSpawns(t *testing.T) {
+	result, err := SolveFile(context.Background(), filepath.Join("..", "tests", "multi.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, turns := schedule(context.Background())
+
+	seen := make(map[string]bool)
+	for i, turnOutput := range turns {
+		plain := strings.Fields(formatTurn(turnOutput))
+		for j, token := range strings.Fields(formatSpawnTurn(turnOutput)) {
+			ant, rest, _ := strings.Cut(token[1:], "-")
+			id, start, spawned := strings.Cut(ant, "@")
+			if spawned == seen[id] {
+				t.Errorf("turn %d: %q, ant %s has moved before: %v", i+1, token, id, seen[id])
+			}
+			seen[id] = true
+			if n, _ := strconv.Atoi(id); spawned && result.Spawns[n-1] != start {
+				t.Errorf("turn %d: %q, the ant spawned in %s", i+1, token, result.Spawns[n-1])
+			}
+			if "L"+id+"-"+rest != plain[j] {
+				t.Errorf("turn %d: %q for %q", i+1, token, plain[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestStrictGrammar feeds farms breaking the canonical grammar to --strict,
+// the default mode takes all of them.
+func TestStrictGrammar(t *testing.T) {
+	const rooms = "##start\ns 0 0\na 1 0\n##end\ne 2 0\n"
+	const tunnels = "s-a\na-e\n"
+
+	for _, tc := range []struct {
+		name, farm, want string
+	}{
+		{"signed ants", "+3\n" + rooms + tunnels, "line 1: number of ants must be a digit"},
+		{"empty line", "3\n" + rooms + "\n" + tunnels, "line 7: empty line"},
+		{"leading space", "3\n" + rooms + " s-a\na-e\n", `line 7: extra whitespace: " s-a"`},
+		{"double space", "3\n##start\ns  0 0\na 1 0\n##end\ne 2 0\n" + tunnels, `line 3: extra whitespace: "s  0 0"`},
+		{"comment after start", "3\n##start\n#the entrance\ns 0 0\na 1 0\n##end\ne 2 0\n" + tunnels,
+			"line 3: ##start must be followed by a room: #the entrance"},
+		{"end at the end", "3\n" + rooms + tunnels + "##end\n", "the file ends before the room of the last ##start or ##end"},
+		{"unknown command", "3\n##teleport a e\n" + rooms + tunnels, "line 2: unknown command: ##teleport"},
+		{"room after tunnels", "3\n" + rooms + "s-a\nb 3 0\na-e\n", "line 8: room after the tunnels: b 3 0"},
+		{"garbage", "3\n" + rooms + tunnels + "hello\n", "line 9: neither a room, a tunnel nor a comment: hello"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Validate(strings.NewReader(tc.farm)); err != nil {
+				t.Fatalf("the default mode rejected it: %v", err)
+			}
+
+			strict = true
+			defer func() { strict = false }()
+			_, err := Validate(strings.NewReader(tc.farm))
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	strict = true
+	defer func() { strict = false }()
+	farm := "3\n#a farm\n" + rooms + "#the tunnels\n" + tunnels + "#group 3 e\n"
+	if _, err := Validate(strings.NewReader(farm)); err != nil {
+		t.Errorf("--strict rejected a canonical farm: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package internal
+
+import "sort"
+
+// Graph is the farm with every room interned to an integer ID. Adjacency is
+// kept in CSR form: the rooms an ant in room i can move to are
+// Adj[Offsets[i]:Offsets[i+1]]. One-way tunnels only appear in one direction,
+// RevOffsets/RevAdj hold the same arcs the other way round.
+// The search and the simulation only work on IDs, names are looked up again
+// when moves are printed.
+type Graph struct {
+	Names      []string       // room name by ID
+	IDs        map[string]int // room ID by name
+	Offsets    []int          // len(Names)+1 offsets into Adj
+	Adj        []int          // neighbour IDs of every room, back to back
+	RevOffsets []int          // len(Names)+1 offsets into RevAdj
+	RevAdj     []int          // IDs of the rooms leading into every room
+	Starts     []int          // start room IDs in file order
+	Ends       []int          // end room IDs in file order
+	IsStart    []bool         // by room ID
+	IsEnd      []bool         // by room ID
+}
+
+// BuildGraph interns the parsed rooms and tunnels. It runs once parsing and
+// pruning are done, the tunnels map is not read by the solver after this.
+func BuildGraph() {
+	graph = newGraph()
+}
+
+func newGraph() *Graph {
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names) // map order is random, IDs have to be reproducible
+
+	g := &Graph{
+		Names:      names,
+		IDs:        make(map[string]int, len(names)),
+		Offsets:    make([]int, len(names)+1),
+		RevOffsets: make([]int, len(names)+1),
+	}
+	for id, name := range names {
+		g.IDs[name] = id
+	}
+
+	incoming := make([][]int, len(names))
+	for id, name := range names {
+		for _, neighbor := range tunnels[name] { // keep the tunnel order of the input
+			g.Adj = append(g.Adj, g.IDs[neighbor])
+			incoming[g.IDs[neighbor]] = append(incoming[g.IDs[neighbor]], id)
+		}
+		g.Offsets[id+1] = len(g.Adj)
+	}
+	for id := range names {
+		g.RevAdj = append(g.RevAdj, incoming[id]...)
+		g.RevOffsets[id+1] = len(g.RevAdj)
+	}
+
+	g.IsStart = make([]bool, len(names))
+	g.IsEnd = make([]bool, len(names))
+	for _, name := range startRooms {
+		g.Starts = append(g.Starts, g.IDs[name])
+		g.IsStart[g.IDs[name]] = true
+	}
+	for _, name := range endRooms {
+		g.Ends = append(g.Ends, g.IDs[name])
+		g.IsEnd[g.IDs[name]] = true
+	}
+	return g
+}
+
+// without returns a copy of g where ants cannot use the tunnels in closed or
+// enter the rooms in blocked. Rooms keep their IDs, so paths and ants carry
+// over from one to the other.
+func (g *Graph) without(closed map[[2]int]bool, blocked []bool) *Graph {
+	reduced := &Graph{
+		Names:      g.Names,
+		IDs:        g.IDs,
+		Offsets:    make([]int, len(g.Names)+1),
+		RevOffsets: make([]int, len(g.Names)+1),
+		Starts:     g.Starts,
+		Ends:       g.Ends,
+		IsStart:    g.IsStart,
+		IsEnd:      g.IsEnd,
+	}
+
+	incoming := make([][]int, len(g.Names))
+	for id := range g.Names {
+		for _, neighbor := range g.Neighbors(id) {
+			if blocked[neighbor] || closed[[2]int{id, neighbor}] {
+				continue
+			}
+			reduced.Adj = append(reduced.Adj, neighbor)
+			incoming[neighbor] = append(incoming[neighbor], id)
+		}
+		reduced.Offsets[id+1] = len(reduced.Adj)
+	}
+	for id := range g.Names {
+		reduced.RevAdj = append(reduced.RevAdj, incoming[id]...)
+		reduced.RevOffsets[id+1] = len(reduced.RevAdj)
+	}
+	return reduced
+}
+
+// withTunnel returns a copy of g with a two-way tunnel dug between a and b.
+func (g *Graph) withTunnel(a, b int) *Graph {
+	dug := &Graph{
+		Names:      g.Names,
+		IDs:        g.IDs,
+		Offsets:    make([]int, len(g.Names)+1),
+		RevOffsets: make([]int, len(g.Names)+1),
+		Starts:     g.Starts,
+		Ends:       g.Ends,
+		IsStart:    g.IsStart,
+		IsEnd:      g.IsEnd,
+	}
+	extra := func(id int) []int {
+		switch id {
+		case a:
+			return []int{b}
+		case b:
+			return []int{a}
+		}
+		return nil
+	}
+	for id := range g.Names {
+		dug.Adj = append(append(dug.Adj, g.Neighbors(id)...), extra(id)...)
+		dug.Offsets[id+1] = len(dug.Adj)
+		dug.RevAdj = append(append(dug.RevAdj, g.Incoming(id)...), extra(id)...)
+		dug.RevOffsets[id+1] = len(dug.RevAdj)
+	}
+	return dug
+}
+
+// Neighbors returns the rooms an ant in id can move to. The slice must not be
+// modified.
+func (g *Graph) Neighbors(id int) []int {
+	return g.Adj[g.Offsets[id]:g.Offsets[id+1]]
+}
+
+// Incoming returns the rooms an ant can reach id from. The slice must not be
+// modified.
+func (g *Graph) Incoming(id int) []int {
+	return g.RevAdj[g.RevOffsets[id]:g.RevOffsets[id+1]]
+}
+
+// distances returns the number of steps from the nearest of the given rooms
+// to every room, -1 when it cannot be reached without going through a room
+// marked in avoid.
+func (g *Graph) distances(from []int, avoid []bool) []int {
+	return g.bfs(from, avoid, g.Neighbors)
+}
+
+// distancesTo returns the number of steps from every room to the nearest of
+// the given rooms, -1 when it cannot be reached without going through a room
+// marked in avoid.
+func (g *Graph) distancesTo(to []int, avoid []bool) []int {
+	return g.bfs(to, avoid, g.Incoming)
+}
+
+func (g *Graph) bfs(from []int, avoid []bool, next func(int) []int) []int {
+	dist := make([]int, len(g.Names))
+	for i := range dist {
+		dist[i] = -1
+	}
+
+	queue := []int{}
+	for _, id := range from {
+		dist[id] = 0
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if avoid[current] {
+			continue // reached, but not walked through
+		}
+
+		for _, neighbor := range next(current) {
+			if dist[neighbor] == -1 {
+				dist[neighbor] = dist[current] + 1
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return dist
+}
+
+// shortestPath returns a shortest path from one of the rooms in from to one
+// of the rooms in to, or nil when there is none. Like the search it never goes
+// through a start room or an end room on the way.
+func (g *Graph) shortestPath(from, to []int) []int {
+	avoid := make([]bool, len(g.Names))
+	for id := range avoid {
+		avoid[id] = g.IsStart[id] || g.IsEnd[id]
+	}
+	for _, id := range to {
+		avoid[id] = false
+	}
+	dist := g.distancesTo(to, avoid)
+
+	current := -1
+	for _, id := range from {
+		if dist[id] > 0 && (current == -1 || dist[id] < dist[current]) {
+			current = id
+		}
+	}
+	if current == -1 {
+		return nil
+	}
+
+	path := []int{current}
+	for dist[current] > 0 {
+		for _, neighbor := range g.Neighbors(current) {
+			if dist[neighbor] == dist[current]-1 && (dist[neighbor] == 0 || !avoid[neighbor]) {
+				current = neighbor
+				break
+			}
+		}
+		path = append(path, current)
+	}
+	return path
+}
+
+// pathIDs interns a path of room names.
+func (g *Graph) pathIDs(names []string) []int {
+	path := make([]int, len(names))
+	for i, name := range names {
+		path[i] = g.IDs[name]
+	}
+	return path
+}
+
+// pathNames translates a path back to room names.
+func (g *Graph) pathNames(path []int) []string {
+	names := make([]string, len(path))
+	for i, id := range path {
+		names[i] = g.Names[id]
+	}
+	return names
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLadderFarm writes a farm of lanes parallel corridors of length rooms
+// each. Every pair of lanes is joined by one rung in the middle, which gives
+// four start -> end paths per pair.
+func writeLadderFarm(tb testing.TB, lanes, length, ants int) string {
+	tb.Helper()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n##start\nstart 0 0\n##end\nend %d 0\n", ants, length+1)
+	for lane := 0; lane < lanes; lane++ {
+		for i := 0; i < length; i++ {
+			fmt.Fprintf(&b, "r%d_%d %d %d\n", lane, i, i+1, lane+1)
+		}
+	}
+	for lane := 0; lane < lanes; lane++ {
+		fmt.Fprintf(&b, "start-r%d_0\nr%d_%d-end\n", lane, lane, length-1)
+		for i := 1; i < length; i++ {
+			fmt.Fprintf(&b, "r%d_%d-r%d_%d\n", lane, i-1, lane, i)
+		}
+		if lane%2 == 1 {
+			fmt.Fprintf(&b, "r%d_%d-r%d_%d\n", lane-1, length/2, lane, length/2)
+		}
+	}
+
+	file := filepath.Join(tb.TempDir(), "farm.txt")
+	if err := os.WriteFile(file, []byte(b.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return file
+}
+
+// loadFarm parses file and builds the graph the same way cmd.Cmd does.
+func loadFarm(tb testing.TB, file string) {
+	tb.Helper()
+	resetFarm()
+	ValidateFileFormat(file)
+	ValidateConnectivity()
+	BuildGraph()
+}
+
+// legacyDFS is the recursive search over room names the solver used before
+// the graph was interned. It is only kept to compare against.
+func legacyDFS(current string, visited map[string]bool, path []string, found *[][]string) {
+	visited[current] = true
+	path = append(path, current)
+
+	if current == endRoom {
+		pathCopy := make([]string, len(path))
+		copy(pathCopy, path)
+		*found = append(*found, pathCopy)
+	}
+
+	for _, neighbor := range tunnels[current] {
+		if !visited[neighbor] {
+			legacyDFS(neighbor, visited, path, found)
+		}
+	}
+
+	visited[current] = false
+}
+
+func TestDFSMatchesLegacySearch(t *testing.T) {
+	loadFarm(t, writeLadderFarm(t, 6, 50, 10))
+
+	var legacy [][]string
+	legacyDFS(startRoom, make(map[string]bool), nil, &legacy)
+
+	allPaths = nil
+	DFS(context.Background(), graph.Starts[0])
+
+	if len(allPaths) != len(legacy) {
+		t.Fatalf("found %d paths, legacy search found %d", len(allPaths), len(legacy))
+	}
+	for i, path := range allPaths {
+		if got, want := strings.Join(graph.pathNames(path), " "), strings.Join(legacy[i], " "); got != want {
+			t.Fatalf("path %d is %q, legacy search found %q", i, got, want)
+		}
+	}
+}
+
+func BenchmarkDFS10k(b *testing.B) {
+	loadFarm(b, writeLadderFarm(b, 10, 1000, 100))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		allPaths = nil
+		DFS(context.Background(), graph.Starts[0])
+	}
+}
+
+func BenchmarkLegacyDFS10k(b *testing.B) {
+	loadFarm(b, writeLadderFarm(b, 10, 1000, 100))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var found [][]string
+		legacyDFS(startRoom, make(map[string]bool), nil, &found)
+	}
+}
+
+func BenchmarkDFSCorridor10k(b *testing.B) {
+	loadFarm(b, writeLadderFarm(b, 1, 10000, 100))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		allPaths = nil
+		DFS(context.Background(), graph.Starts[0])
+	}
+}
+
+func BenchmarkSimulate10k(b *testing.B) {
+	loadFarm(b, writeLadderFarm(b, 10, 1000, 100))
+	allPaths = nil
+	DFS(context.Background(), graph.Starts[0])
+	paths, antsPerPath := planPaths(selectStepPaths(context.Background(), allPaths))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		simulateAnts(paths, antsPerPath)
+	}
+}
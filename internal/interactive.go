@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const debuggerHelp = `commands:
+  s, step [n]       play the next n turns (default 1), an empty line steps too
+  c, continue       play until a breakpoint or the end
+  j, jump <turn>    go to the end of a turn, backwards too
+  t, transit        list the ants in transit
+  o, occupied       list the occupied rooms
+  p, paths          list the paths with their spawn counts
+  w, where <ant>    tell where an ant is
+  b, break <ant> <room>
+                    stop when the ant enters the room, without arguments list
+                    the breakpoints
+  d, delete <n>     remove breakpoint n
+  h, help           show this help
+  q, quit           leave
+`
+
+// breakpoint stops the debugger when an ant enters a room.
+type breakpoint struct {
+	ant  int
+	room int
+}
+
+// debugger steps through simulateAnts for --interactive.
+type debugger struct {
+	paths       [][]int
+	quota       []int
+	sim         *simulation
+	finished    bool // the last step found nothing left to do
+	breakpoints []breakpoint
+	out         io.Writer
+}
+
+// Interactive plans the schedule like Simulate and then lets the user play it
+// turn by turn, reading commands from in. --events is refused by ParseFlags.
+func Interactive(ctx context.Context, in io.Reader, out io.Writer) {
+	paths, quota, turns := plannedSchedule(ctx)
+	if turns == nil {
+		return
+	}
+
+	d := &debugger{paths: paths, quota: quota, sim: newSimulation(paths, quota), out: out}
+	fmt.Fprintf(out, "%d ants on %d paths, type h for help\n", ants, len(paths))
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "(lemin) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			args = []string{"step"}
+		}
+
+		if !d.run(args) {
+			return
+		}
+	}
+}
+
+// run executes one command, it returns false to quit.
+func (d *debugger) run(args []string) bool {
+	switch args[0] {
+	case "s", "step":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				fmt.Fprintln(d.out, "step needs a number of turns > 0")
+				return true
+			}
+		}
+		for i := 0; i < n && d.next(false); i++ {
+		}
+
+	case "c", "continue":
+		for d.next(false) {
+		}
+
+	case "j", "jump":
+		turn, err := strconv.Atoi(argument(args, 1))
+		if err != nil || turn < 0 {
+			fmt.Fprintln(d.out, "jump needs a turn >= 0")
+			return true
+		}
+		d.jump(turn)
+
+	case "t", "transit":
+		d.printTransit()
+
+	case "o", "occupied":
+		var names []string
+		for room, taken := range d.sim.occupied {
+			if taken {
+				names = append(names, graph.Names[room])
+			}
+		}
+		fmt.Fprintf(d.out, "occupied: %s\n", strings.Join(names, " "))
+
+	case "p", "paths":
+		for i, path := range d.paths {
+			fmt.Fprintf(d.out, "path %d: %s, spawned %d of %d\n", i+1, strings.Join(graph.pathNames(path), "-"), d.sim.spawned[i], d.quota[i])
+		}
+
+	case "w", "where":
+		ant, err := strconv.Atoi(argument(args, 1))
+		if err != nil || ant < 1 || ant > ants {
+			fmt.Fprintf(d.out, "where needs an ant between 1 and %d\n", ants)
+			return true
+		}
+		fmt.Fprintln(d.out, d.where(ant))
+
+	case "b", "break":
+		if len(args) == 1 {
+			for i, bp := range d.breakpoints {
+				fmt.Fprintf(d.out, "breakpoint %d: ant %d enters %s\n", i+1, bp.ant, graph.Names[bp.room])
+			}
+			return true
+		}
+		ant, err := strconv.Atoi(argument(args, 1))
+		room, ok := graph.IDs[argument(args, 2)]
+		if err != nil || !ok {
+			fmt.Fprintln(d.out, "break needs an ant and a room")
+			return true
+		}
+		d.breakpoints = append(d.breakpoints, breakpoint{ant: ant, room: room})
+		fmt.Fprintf(d.out, "breakpoint %d: ant %d enters %s\n", len(d.breakpoints), ant, graph.Names[room])
+
+	case "d", "delete":
+		n, err := strconv.Atoi(argument(args, 1))
+		if err != nil || n < 1 || n > len(d.breakpoints) {
+			fmt.Fprintln(d.out, "no such breakpoint")
+			return true
+		}
+		d.breakpoints = append(d.breakpoints[:n-1], d.breakpoints[n:]...)
+
+	case "h", "help":
+		fmt.Fprint(d.out, debuggerHelp)
+
+	case "q", "quit":
+		return false
+
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, type h for help\n", args[0])
+	}
+	return true
+}
+
+// argument returns args[i], or "" when there are not that many.
+func argument(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// next plays one turn and, unless silent, prints it and checks the
+// breakpoints. It returns false when the simulation is over or a breakpoint
+// was hit.
+func (d *debugger) next(silent bool) bool {
+	if d.finished || !d.sim.step() {
+		if !d.finished {
+			fmt.Fprintf(d.out, "simulation finished after %d turns, %d of %d ants delivered\n", len(d.sim.turns), delivered(d.sim.turns), ants)
+		}
+		d.finished = true
+		return false
+	}
+
+	if silent {
+		return true
+	}
+	turn := len(d.sim.turns)
+	moves := d.sim.turns[turn-1]
+	fmt.Fprintf(d.out, "turn %d: %s\n", turn, formatTurn(moves))
+
+	for i, bp := range d.breakpoints {
+		for _, m := range moves {
+			if m.Ant == bp.ant && m.Room == bp.room {
+				fmt.Fprintf(d.out, "breakpoint %d: ant %d entered %s on turn %d\n", i+1, bp.ant, graph.Names[bp.room], turn)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// jump replays the simulation up to the end of turn, starting over when the
+// turn is behind the current one. Breakpoints are ignored on the way.
+func (d *debugger) jump(turn int) {
+	if turn < len(d.sim.turns) {
+		d.sim = newSimulation(d.paths, d.quota)
+		d.finished = false
+	}
+	for len(d.sim.turns) < turn && d.next(true) {
+	}
+	if len(d.sim.turns) < turn {
+		return // next told that the simulation finished
+	}
+	fmt.Fprintf(d.out, "at turn %d\n", turn)
+}
+
+func (d *debugger) printTransit() {
+	if len(d.sim.antsInTransit) == 0 {
+		fmt.Fprintln(d.out, "no ants in transit")
+		return
+	}
+	for _, ant := range d.sim.antsInTransit {
+		path := d.paths[ant.Path]
+		fmt.Fprintf(d.out, "ant %d in %s, step %d of %d on path %d\n", ant.ID, graph.Names[path[ant.Index]], ant.Index, len(path)-1, ant.Path+1)
+	}
+}
+
+// where describes the position of ant.
+func (d *debugger) where(ant int) string {
+	if ant >= d.sim.nextAnt {
+		return fmt.Sprintf("ant %d has not spawned yet", ant)
+	}
+	for _, a := range d.sim.antsInTransit {
+		if a.ID == ant {
+			path := d.paths[a.Path]
+			return fmt.Sprintf("ant %d is in %s on path %d (%s)", ant, graph.Names[path[a.Index]], a.Path+1, strings.Join(graph.pathNames(path), "-"))
+		}
+	}
+	for turn := len(d.sim.turns) - 1; turn >= 0; turn-- {
+		for _, m := range d.sim.turns[turn] {
+			if m.Ant == ant {
+				return fmt.Sprintf("ant %d reached %s on turn %d", ant, graph.Names[m.Room], turn+1)
+			}
+		}
+	}
+	return fmt.Sprintf("ant %d is nowhere", ant) // cannot happen, spawned ants have moved
+}
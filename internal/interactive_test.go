@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestInteractive drives the debugger through a session on tests/release.txt
+// and checks that it agrees with the printed schedule.
+func TestInteractive(t *testing.T) {
+	loadFarm(t, filepath.Join("..", "tests", "release.txt"))
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(context.Background())
+	FindBestPaths(context.Background())
+
+	session := strings.Join([]string{
+		"w 1",
+		"",
+		"w 1",
+		"b 4 e",
+		"c",
+		"where 4",
+		"j 1",
+		"t",
+		"j 100",
+		"bogus",
+		"q",
+	}, "\n")
+	var out strings.Builder
+	Interactive(context.Background(), strings.NewReader(session), &out)
+
+	for _, want := range []string{
+		"ant 1 has not spawned yet",
+		"turn 1: L1-a L2-b L3-c",
+		"ant 1 is in a on path 1 (s-a-e)",
+		"turn 3: L3-e L4-e\nbreakpoint 1: ant 4 entered e on turn 3",
+		"ant 4 reached e on turn 3",
+		"at turn 1",
+		"ant 3 in c, step 1 of 3 on path 3",
+		"simulation finished after 8 turns, 12 of 12 ants delivered",
+		`unknown command "bogus"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("session output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
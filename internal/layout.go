@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"sort"
+)
+
+// Point is a display position.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// layoutSpacing is the distance between two layers, and between two rooms of
+// a layer.
+const layoutSpacing = 10
+
+// displayRooms returns the rooms sorted by name for the exporters. With
+// --layout auto every room also carries the position computed by autoLayout,
+// its own X and Y are kept as they were given.
+func displayRooms() []Room {
+	list := make([]Room, 0, len(rooms))
+	for _, room := range rooms {
+		list = append(list, room)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	if layout == "auto" {
+		positions := autoLayout()
+		for i := range list {
+			p := positions[list[i].Name]
+			list[i].Display = &p
+		}
+	}
+	return list
+}
+
+// autoLayout places the rooms in layers from the graph alone: a room goes in
+// the layer of its distance to the nearest start room, the end rooms in the
+// last layer, so the ants move from left to right. Rooms no start room
+// reaches are laid out the same way, each group from layer 1. Pruned tunnels
+// count, so a dead end is drawn beside the room it hangs off. Within a layer
+// rooms are ordered by the mean position of their neighbours in the previous
+// layer, which untangles most tunnels.
+func autoLayout() map[string]Point {
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// tunnels either way, one-way tunnels are drawn like the others
+	linked := make(map[string][]string)
+	for room, neighbors := range drawnTunnels() {
+		for _, neighbor := range neighbors {
+			linked[room] = append(linked[room], neighbor)
+			if oneWay[[2]string{room, neighbor}] {
+				linked[neighbor] = append(linked[neighbor], room)
+			}
+		}
+	}
+	for _, neighbors := range linked {
+		sort.Strings(neighbors)
+	}
+
+	isEnd := make(map[string]bool)
+	for _, end := range endRooms {
+		isEnd[end] = true
+	}
+
+	layer := make(map[string]int)
+	bfs := func(from []string, base int) {
+		queue := []string{}
+		for _, room := range from {
+			layer[room] = base
+			queue = append(queue, room)
+		}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if isEnd[current] {
+				continue // ants stop there, so does the layout
+			}
+			for _, neighbor := range linked[current] {
+				if _, seen := layer[neighbor]; !seen {
+					layer[neighbor] = layer[current] + 1
+					queue = append(queue, neighbor)
+				}
+			}
+		}
+	}
+	bfs(startRooms, 0)
+	for _, name := range names {
+		if _, seen := layer[name]; !seen && !isEnd[name] {
+			bfs([]string{name}, 1)
+		}
+	}
+
+	// end rooms get a layer of their own on the right
+	for _, end := range endRooms {
+		delete(layer, end)
+	}
+	last := 0
+	for _, l := range layer {
+		last = max(last, l)
+	}
+	for _, end := range endRooms {
+		layer[end] = last + 1
+	}
+
+	layers := make([][]string, last+2)
+	for _, name := range names {
+		layers[layer[name]] = append(layers[layer[name]], name)
+	}
+
+	// barycenter ordering, a few sweeps from left to right
+	index := make(map[string]int)
+	for _, rooms := range layers {
+		for i, name := range rooms {
+			index[name] = i
+		}
+	}
+	for sweep := 0; sweep < 4; sweep++ {
+		for l := 1; l < len(layers); l++ {
+			center := make(map[string]float64)
+			for _, name := range layers[l] {
+				sum, n := 0.0, 0
+				for _, neighbor := range linked[name] {
+					if layer[neighbor] == l-1 {
+						sum += float64(index[neighbor])
+						n++
+					}
+				}
+				center[name] = float64(index[name])
+				if n > 0 {
+					center[name] = sum / float64(n)
+				}
+			}
+			sort.SliceStable(layers[l], func(i, j int) bool {
+				return center[layers[l][i]] < center[layers[l][j]]
+			})
+			for i, name := range layers[l] {
+				index[name] = i
+			}
+		}
+	}
+
+	// layers are centred on the widest one
+	widest := 0
+	for _, rooms := range layers {
+		widest = max(widest, len(rooms))
+	}
+	positions := make(map[string]Point, len(names))
+	for l, rooms := range layers {
+		offset := (widest - len(rooms)) * layoutSpacing / 2
+		for i, name := range rooms {
+			positions[name] = Point{X: l * layoutSpacing, Y: offset + i*layoutSpacing}
+		}
+	}
+	return positions
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAutoLayout lays out tests/test2.txt: the start room on the left, the end
+// room alone on the right, no two rooms on the same spot, and the coordinates
+// of the input kept.
+func TestAutoLayout(t *testing.T) {
+	layout = "auto"
+	defer func() { layout = "" }()
+
+	file, err := os.Open(filepath.Join("..", "tests", "test2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	farm, err := Validate(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken := make(map[Point]string)
+	right := 0
+	for _, room := range farm.Rooms {
+		if room.Display == nil {
+			t.Fatalf("room %s has no display position", room.Name)
+		}
+		if other, ok := taken[*room.Display]; ok {
+			t.Errorf("rooms %s and %s are both drawn at %v", other, room.Name, *room.Display)
+		}
+		taken[*room.Display] = room.Name
+		right = max(right, room.Display.X)
+	}
+
+	for _, room := range farm.Rooms {
+		switch room.Name {
+		case "start":
+			if room.Display.X != 0 || room.X != 1 || room.Y != 6 {
+				t.Errorf("start room: got %+v at %v", room, *room.Display)
+			}
+		case "end":
+			if room.Display.X != right {
+				t.Errorf("end room drawn at x=%d, the rightmost layer is %d", room.Display.X, right)
+			}
+		default:
+			if room.Display.X == 0 || room.Display.X == right {
+				t.Errorf("room %s drawn in the layer of the start or end room", room.Name)
+			}
+		}
+	}
+}
+
+// TestAutoLayoutPruned lays out a corridor with a dead end x off d once it was
+// pruned, the way CreateJson does: x still goes next to d.
+func TestAutoLayoutPruned(t *testing.T) {
+	layout = "auto"
+	defer func() { layout = "" }()
+
+	resetFarm()
+	farm := "1\n##start\ns 0 0\na 1 0\nb 2 0\nc 3 0\nd 4 0\nx 4 1\n##end\ne 5 0\ns-a\na-b\nb-c\nc-d\nd-e\nd-x\n"
+	if err := parseFarm(strings.NewReader(farm)); err != nil {
+		t.Fatal(err)
+	}
+	PruneGraph()
+
+	at := make(map[string]Point)
+	for _, room := range displayRooms() {
+		at[room.Name] = *room.Display
+	}
+	if at["x"].X != at["d"].X+layoutSpacing {
+		t.Errorf("x drawn at %v, d at %v", at["x"], at["d"])
+	}
+}
+
+// TestAutoLayoutMissingCoordinates reads rooms given without coordinates, they
+// are only accepted with --layout auto and placed like the others.
+func TestAutoLayoutMissingCoordinates(t *testing.T) {
+	const farm = "1\n##start\ns\na - -\nb 3 3\n##end\ne\ns-a\na-b\nb-e\n"
+	if _, err := Validate(strings.NewReader(farm)); err == nil {
+		t.Error("rooms without coordinates accepted without --layout auto")
+	}
+
+	layout = "auto"
+	defer func() { layout = "" }()
+	got, err := Validate(strings.NewReader(farm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Warnings) != 0 {
+		t.Errorf("got warnings %q", got.Warnings)
+	}
+	taken := make(map[Point]string)
+	for _, room := range got.Rooms {
+		if room.Unplaced != (room.Name != "b") {
+			t.Errorf("room %s: unplaced is %v", room.Name, room.Unplaced)
+		}
+		if other, ok := taken[*room.Display]; ok {
+			t.Errorf("rooms %s and %s are both drawn at %v", other, room.Name, *room.Display)
+		}
+		taken[*room.Display] = room.Name
+	}
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"time"
+)
+
+//go:embed live.html
+var livePage []byte
+
+// live streams the schedule to the browsers watching --live, nil otherwise.
+var live *liveHub
+
+// liveEvent is one server-sent event, data is JSON.
+type liveEvent struct {
+	name string
+	data []byte
+}
+
+// liveTurn is the data of a "turn" event.
+type liveTurn struct {
+	Turn  int    `json:"turn"`
+	Moves []Move `json:"moves"`
+}
+
+// liveHub hands every event to all connected viewers. It keeps the events
+// published so far, so a viewer joining late (or reconnecting) replays them
+// from the start.
+type liveHub struct {
+	mu      sync.Mutex
+	history []liveEvent
+	viewers map[chan liveEvent]struct{}
+
+	turns [][]antMove // turns streamed so far, only touched by the simulation
+}
+
+func newLiveHub() *liveHub {
+	return &liveHub{viewers: make(map[chan liveEvent]struct{})}
+}
+
+// publish sends an event to every viewer and records it for the next ones.
+func (h *liveHub) publish(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		Log("live: "+err.Error(), "error")
+		return
+	}
+	ev := liveEvent{name: name, data: data}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history = append(h.history, ev)
+	for ch := range h.viewers {
+		select {
+		case ch <- ev:
+		default:
+			// the viewer is too slow, drop it; the browser reconnects and
+			// gets the history again
+			delete(h.viewers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *liveHub) subscribe() ([]liveEvent, chan liveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan liveEvent, 256)
+	h.viewers[ch] = struct{}{}
+	return slices.Clone(h.history), ch
+}
+
+func (h *liveHub) unsubscribe(ch chan liveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.viewers[ch]; ok {
+		delete(h.viewers, ch)
+		close(ch)
+	}
+}
+
+// turn streams the next turn of the schedule.
+func (h *liveHub) turn(moves []antMove) {
+	h.turns = append(h.turns, moves)
+	named := make([]Move, len(moves))
+	for i, m := range moves {
+		named[i] = Move{Turn: len(h.turns), Ant: m.Ant, From: graph.Names[m.From], To: graph.Names[m.Room]}
+	}
+	h.publish("turn", liveTurn{Turn: len(h.turns), Moves: named})
+}
+
+// restart throws away the turns streamed so far, the viewers start over from
+// the farm. Late viewers never see the dropped turns.
+func (h *liveHub) restart() {
+	if len(h.turns) == 0 {
+		return
+	}
+	h.turns = nil
+	h.mu.Lock()
+	for i, ev := range h.history {
+		if ev.name == "turn" {
+			h.history = h.history[:i]
+			break
+		}
+	}
+	h.mu.Unlock()
+	h.publish("restart", struct{}{})
+}
+
+// finish makes sure the viewers end up with the printed schedule. It is
+// streamed again when it is not the one played live: the portfolio, an event
+// script or the disjoint retry all settle on their schedule afterwards.
+func (h *liveHub) finish(turns [][]antMove) {
+	if !slices.EqualFunc(h.turns, turns, slices.Equal[[]antMove]) {
+		h.restart()
+		for _, moves := range turns {
+			h.turn(moves)
+		}
+	}
+	h.publish("done", map[string]int{"turns": len(turns)})
+}
+
+func (h *liveHub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(livePage)
+	})
+	mux.HandleFunc("GET /events", h.serveEvents)
+	return mux
+}
+
+// serveEvents streams the events to one viewer as server-sent events.
+func (h *liveHub) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	history, ch := h.subscribe()
+	defer h.unsubscribe(ch)
+	for _, ev := range history {
+		writeEvent(w, ev)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, ev)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev liveEvent) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+}
+
+// startLive serves the live view on liveAddr and publishes the farm. The
+// returned function waits for Ctrl-C once the schedule is done, so the
+// viewers can finish watching, then shuts the server down.
+func startLive() func() {
+	ln, err := net.Listen("tcp", liveAddr)
+	if err != nil {
+		Log("live: "+err.Error(), "error")
+		return func() {}
+	}
+	live = newLiveHub()
+	live.publish("farm", farmInfo())
+
+	server := &http.Server{Handler: live.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(ln)
+	Log("live view on http://"+ln.Addr().String(), "info")
+
+	return func() {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		Log("live view stays up until Ctrl-C", "info")
+		<-ctx.Done()
+		server.Close()
+		live = nil
+	}
+}
+
+// simulateLive is simulateAnts streaming every turn to the live viewers as
+// soon as it is played.
+func simulateLive(paths [][]int, quota []int) [][]antMove {
+	if live == nil || len(paths) == 0 {
+		return simulateAnts(paths, quota)
+	}
+	live.restart()
+	sim := newSimulation(paths, quota)
+	for sim.step() {
+		live.turn(sim.turns[len(sim.turns)-1])
+	}
+	return sim.turns
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readLive reads the event stream of a viewer up to the "done" event and
+// returns the turns it got.
+func readLive(url string) ([]liveTurn, error) {
+	res, err := http.Get(url + "/events")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var turns []liveTurn
+	var name string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			name = event
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "restart":
+			turns = nil
+		case "turn":
+			var turn liveTurn
+			if err := json.Unmarshal([]byte(data), &turn); err != nil {
+				return nil, err
+			}
+			turns = append(turns, turn)
+		case "done":
+			return turns, nil
+		}
+	}
+	return nil, errors.New("the stream ended before the done event")
+}
+
+// TestLive streams tests/test.txt to a viewer watching from the start and to
+// one joining after the schedule is done, both see the printed schedule.
+func TestLive(t *testing.T) {
+	loadFarm(t, filepath.Join("..", "tests", "test.txt"))
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(context.Background())
+	FindBestPaths(context.Background())
+
+	live = newLiveHub()
+	defer func() { live = nil }()
+	live.publish("farm", farmInfo())
+	server := httptest.NewServer(live.handler())
+	defer server.Close()
+
+	type viewed struct {
+		turns []liveTurn
+		err   error
+	}
+	early := make(chan viewed)
+	go func() {
+		turns, err := readLive(server.URL)
+		early <- viewed{turns, err}
+	}()
+	for { // wait for the viewer before streaming
+		live.mu.Lock()
+		n := len(live.viewers)
+		live.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, turns := schedule(context.Background())
+	live.finish(turns)
+
+	late, err := readLive(server.URL)
+	for _, v := range []viewed{<-early, {late, err}} {
+		if v.err != nil {
+			t.Fatal(v.err)
+		}
+		got := v.turns
+		if len(got) != len(turns) {
+			t.Fatalf("viewer got %d turns, want %d", len(got), len(turns))
+		}
+		for i, turn := range got {
+			if turn.Turn != i+1 || len(turn.Moves) != len(turns[i]) {
+				t.Errorf("turn %d: got %+v", i+1, turn)
+			}
+		}
+	}
+}
+
+// TestLiveFarmUnpruned checks the farm sent to the viewers still has the
+// tunnels PruneGraph dropped, or the pruned rooms are drawn alone.
+func TestLiveFarmUnpruned(t *testing.T) {
+	resetFarm()
+	if err := parseFarm(strings.NewReader("1\n##start\ns 0 0\na 1 0\ndead 1 1\n##end\ne 2 0\ns-a\na-e\na-dead\n")); err != nil {
+		t.Fatal(err)
+	}
+	PruneGraph()
+
+	var got []string
+	for _, tunnel := range farmInfo().Tunnels {
+		got = append(got, tunnel.From+"-"+tunnel.To)
+	}
+	if want := "a-dead a-e a-s"; strings.Join(got, " ") != want {
+		t.Errorf("got tunnels %q, want %q", got, want)
+	}
+	if _, ok := tunnels["dead"]; ok {
+		t.Error("dead was not pruned")
+	}
+}
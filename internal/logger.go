@@ -2,12 +2,30 @@ package internal
 
 import (
 	"fmt"
+	"os"
 )
 
+// quiet hides debug and info output, errors are always printed
+var quiet = false
+
+// SetQuiet turns debug and info output off, for commands that print their
+// own report.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
 func Log(s string, errType string) {
+	if quiet && errType != "error" {
+		return
+	}
 	switch errType {
 	case "error":
 		fmt.Printf("[ERROR] %s\n", s)
+	case "warning":
+		// on stderr, so they do not end up in the moves
+		fmt.Fprintf(os.Stderr, "[WARNING] %s\n", s)
+	case "info":
+		fmt.Printf("[INFO] %s\n", s)
 	case "debug":
 		// return // comment this line to show debug output
 		fmt.Printf("[DEBUG] %s\n", s)
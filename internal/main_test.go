@@ -0,0 +1,11 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	SetQuiet(true) // keep test output readable
+	os.Exit(m.Run())
+}
@@ -1,44 +1,116 @@
 package internal
 
+import "time"
+
+// portfolioTimeout bounds how long the portfolio waits for its strategies
+// when no --timeout is given.
+var portfolioTimeout = 30 * time.Second
+
 var (
-	visualizer = false // default visualization is off data is printed on terminal
-	ants       int     // number of ants
+	visualizer  = false       // default visualization is off data is printed on terminal
+	portfolio   = false       // run every registered strategy and keep the best schedule
+	interactive = false       // step through the simulation instead of printing it
+	showSpawns  = false       // --show-spawns prints the first move of an ant as L<ant>@<start>-<room>
+	liveAddr    = ""          // --live address streaming the simulation to a browser
+	timeout     time.Duration // time budget for the search, 0 means no limit
+	maxPaths    = 0           // stop the search after this many paths, 0 means no limit
+	maxPathLen  = -1          // extra steps allowed over the shortest path, -1 means no limit
+	strict      = false       // --strict enforces the canonical grammar and turns the warnings about the input into errors
+	layout      = ""          // "auto" computes display coordinates instead of drawing the input ones
+	objective   = ""          // metric to minimise among the fastest schedules: "moves", "arrivals" or "" for none
+	ants        int           // number of ants
+
+	releaseFlag []release // --release schedule, replaces the #release line of the farm
+	eventScript []event   // --events script of tunnel and room failures
 
 	expectingStartRoom bool
-	startRoomFound     = false
+	expectingEndRoom   bool
+	pendingStartAnts   int  // ant count given on the last ##start line
+	tunnelsStarted     bool // a tunnel was read, only tunnels may follow with --strict
 
-	expectingEndRoom bool
-	endRoomFound     = false
+	startRoom string // saves start room name (the first one)
+	endRoom   string // saves quick room name (the first one)
 
-	startRoom string // saves start room name
-	endRoom   string // saves quick room name
+	startRooms []string // every ##start room in file order
+	endRooms   []string // every ##end room in file order
+
+	startAnts   = make(map[string]int) // ants spawning in each start room, from "##start <ants>"
+	groupLines  []antGroup             // "#group <ants> <end> [start]" lines, checked once the rooms are known
+	releaseLine []release              // the "#release" schedule of the farm
 )
 
-// tunnels created after reading the file
+// when the ants become available, nil when they all are from the start
+var releases []release
+
+// things in the input the solver accepts but a spec may not, see checkGeometry
+var warnings []string
+
+// ants split by where they may spawn and where they have to go, built at the
+// end of parsing
+var antGroups []antGroup
+
+// tunnels created after reading the file, tunnels[a] lists the rooms an ant
+// in a can move to
 var tunnels = make(map[string][]string)
 
+// inputTunnels keeps tunnels as they were read once PruneGraph drops the
+// useless ones, nil before that, see drawnTunnels
+var inputTunnels map[string][]string
+
+// oneWay marks the tunnels written as a>b, they are only stored in tunnels[a]
+var oneWay = make(map[[2]string]bool)
+
 // used for quick room lookup
 var rooms = make(map[string]Room)
 
 // Rooms created after reading the file
 type Room struct {
-	Name string `json:"name"`
-	X    int    `json:"x"`
-	Y    int    `json:"y"`
+	Name     string `json:"name"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Unplaced bool   `json:"unplaced,omitempty"` // given without coordinates, X and Y are 0, only with --layout auto
+
+	// Display is the position autoLayout computed with --layout auto. The
+	// viewers and the visualizer draw the room there when it is set and at X
+	// and Y otherwise.
+	Display *Point            `json:"display,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"` // set by directives like ##label, see directives.go
 }
 
+// the farm interned to room IDs, built after parsing
+var graph *Graph
+
 // contains all the paths from DFS
-var allPaths [][]string
+var allPaths [][]int
 
+// shortest path found by the BFS in ValidateConnectivity
+var shortestPath []string
+
+// shortest path from each start room that reaches an end room
+var shortestPaths [][]string
+
+// search bounds derived from --max-path-len
 var (
-	bestStepPath          []string   // best path from step calculator
-	bestStepDisjointPaths [][]string // includes bestStepPath and others
+	stepLimit = -1  // longest path allowed, in steps
+	distToEnd []int // steps from each room to the nearest end room, -1 when unreachable
+)
+
+// set when the time budget ran out before the search finished
+var timedOut = false
+
+// turns of the schedule before the --events failures were applied
+var baselineTurns = 0
+
+var (
+	bestStepPath          []int   // best path from step calculator
+	bestStepDisjointPaths [][]int // includes bestStepPath and others
 )
 
 //-------------------------------------------------------------------------
 /// Create json for python visualizer
 
-// Move is a JSON-serializable record of a single ant move.
+// Move is a JSON-serializable record of a single ant move. The first move of
+// an ant comes from the start room it spawned in.
 type Move struct {
 	Turn int    `json:"turn"`
 	Ant  int    `json:"ant"`
@@ -49,5 +121,35 @@ type Move struct {
 // allMoves accumulates every Move in visualizer mode.
 var allMoves []Move
 
-// lastRoom[antID] = the room that antID occupied at the end of the last turn.
-var lastRoom = make(map[int]string)
+// resetFarm clears everything read from a farm file and computed from it, so
+// another farm can be loaded in the same process. Flags are left as they are.
+func resetFarm() {
+	ants = 0
+	expectingStartRoom, expectingEndRoom = false, false
+	pendingStartAnts = 0
+	tunnelsStarted = false
+	startRoom, endRoom = "", ""
+	startRooms, endRooms = nil, nil
+	startAnts = make(map[string]int)
+	groupLines = nil
+	releaseLine, releases = nil, nil
+	antGroups = nil
+	warnings = nil
+	pendingAnnotations = nil
+	tunnelMeta = make(map[[2]string]map[string]string)
+
+	tunnels = make(map[string][]string)
+	inputTunnels = nil
+	oneWay = make(map[[2]string]bool)
+	rooms = make(map[string]Room)
+	graph = nil
+
+	allPaths = nil
+	shortestPath, shortestPaths = nil, nil
+	timedOut = false
+	baselineTurns = 0
+	stepLimit, distToEnd = -1, nil
+	bestStepPath, bestStepDisjointPaths = nil, nil
+
+	allMoves = nil
+}
@@ -1,27 +1,155 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 )
 
-func Simulate() {
+func Simulate(ctx context.Context) {
+	if interactive {
+		Interactive(ctx, os.Stdin, os.Stdout)
+		return
+	}
+	if liveAddr != "" {
+		wait := startLive()
+		defer wait()
+	}
+	_, turns := schedule(ctx)
+	if len(startRooms) > 1 {
+		logSpawns(turns)
+	}
+	printTurns(turns)
+	if live != nil {
+		live.finish(turns)
+	}
+}
+
+// logSpawns tells how many ants left each start room. The start room of every
+// ant is in the moves with --show-spawns, in Result.Spawns and in the "from"
+// of the first move in simulation.json.
+func logSpawns(turns [][]antMove) {
+	spawned := make(map[int]int)
+	for _, start := range spawnRooms(turns) {
+		if start != -1 { // -1 never left, the events kept it in
+			spawned[start]++
+		}
+	}
+	for _, start := range graph.Starts {
+		Log(fmt.Sprintf("%d ants spawned in %s", spawned[start], graph.Names[start]), "info")
+	}
+}
+
+// spawnRooms returns the start room of every ant, ant n at index n-1, -1 for
+// an ant that never moved.
+func spawnRooms(turns [][]antMove) []int {
+	spawns := make([]int, ants)
+	for i := range spawns {
+		spawns[i] = -1
+	}
+	seen := make([]bool, ants+1)
+	for _, turnOutput := range turns {
+		for _, m := range turnOutput {
+			if !seen[m.Ant] {
+				seen[m.Ant] = true
+				spawns[m.Ant-1] = m.From
+			}
+		}
+	}
+	return spawns
+}
+
+// schedule computes the moves of every turn without printing them, from the
+// paths picked by FindBestPaths or from the portfolio. It also returns the
+// paths the ants were sent through. With an event script the schedule is
+// simulated again with the failures and compared with the one without.
+func schedule(ctx context.Context) ([][]int, [][]antMove) {
+	paths, _, turns := plannedSchedule(ctx)
+	if eventScript == nil || turns == nil {
+		return paths, turns
+	}
+
+	baselineTurns = len(turns)
+	turns = simulateEvents(ctx, paths)
+	Log(fmt.Sprintf("the events cost %d extra turns: %d instead of %d", len(turns)-baselineTurns, len(turns), baselineTurns), "info")
+	if n := delivered(turns); n < ants {
+		Log(fmt.Sprintf("%d of %d ants never reached an end room", ants-n, ants), "info")
+	}
+	return paths, turns
+}
+
+// plannedSchedule is schedule without the events, it also returns how many
+// ants each path carries.
+func plannedSchedule(ctx context.Context) ([][]int, []int, [][]antMove) {
+	if portfolio {
+		return runPortfolio(ctx)
+	}
+	return defaultSchedule(ctx)
+}
+
+// defaultSchedule plans the paths picked by FindBestPaths and simulates them.
+// It also returns how many ants each path carries.
+func defaultSchedule(ctx context.Context) ([][]int, []int, [][]antMove) {
+	paths, antsPerPath := planPaths(bestStepDisjointPaths)
+
+	// hand off to simulateAnts (now with quotas)
+	turns := simulateLive(paths, antsPerPath)
+	if delivered(turns) < ants {
+		// Overlapping paths can block each other for good, room-disjoint ones cannot
+		Log("ants got stuck on overlapping paths, retrying with disjoint paths", "debug")
+		paths, antsPerPath = planPaths(disjointStrategy(ctx))
+		turns = simulateLive(paths, antsPerPath)
+	}
+	return paths, antsPerPath, turns
+}
+
+// planPaths decides how many of the selected paths are used and how many ants
+// each of them carries. With per-start ant counts every group is planned on
+// the paths of its own start room and the quotas are put back to back.
+func planPaths(selected [][]int) ([][]int, []int) {
+	paths, quotas, _ := planLeft(selected, groupSizes())
+	return paths, quotas
+}
+
+// planLeft is planPaths for left[i] ants of group i. It also returns the group
+// every path belongs to. Groups without ants or without a path are skipped.
+func planLeft(selected [][]int, left []int) ([][]int, []int, []int) {
+	var paths [][]int
+	var quotas, groups []int
+	for i, group := range antGroups {
+		own := groupPaths(selected, group)
+		if left[i] == 0 || len(own) == 0 {
+			continue
+		}
+		groupPaths, groupQuotas := planGroup(own, left[i])
+		paths = append(paths, groupPaths...)
+		quotas = append(quotas, groupQuotas...)
+		for range groupPaths {
+			groups = append(groups, i)
+		}
+	}
+	return paths, quotas, groups
+}
+
+// planGroup is planPaths for totalAnts ants that may use any of selected.
+func planGroup(selected [][]int, totalAnts int) ([][]int, []int) {
 	// Determine the number of paths to use based on the number of ants
 	numPaths := 1
-	if ants > 3 && ants <= 6 {
+	if totalAnts > 3 && totalAnts <= 6 {
 		numPaths = 3
 	} else {
-		numPaths = len(bestStepDisjointPaths)
+		numPaths = len(selected)
 	}
 
 	// Clamp to available paths
-	if numPaths > len(bestStepDisjointPaths) {
-		numPaths = len(bestStepDisjointPaths)
+	if numPaths > len(selected) {
+		numPaths = len(selected)
 	}
 
 	// 1) slice out the paths we will actually use
-	paths := bestStepDisjointPaths[:numPaths]
+	paths := selected[:numPaths]
 
 	// 2) compute each path’s “cost” (number of edges)
 	costs := make([]int, len(paths))
@@ -29,11 +157,44 @@ func Simulate() {
 		costs[i] = len(p) - 1
 	}
 
-	// 3) compute exactly how many ants each path should carry
-	antsPerPath := ComputeAntsPerPath(costs, ants)
+	// 3) compute exactly how many ants each path should carry, the release
+	// schedule applies to all the ants, buildReleases refuses it with several
+	// groups
+	if releases != nil && len(antGroups) == 1 && totalAnts == ants {
+		return paths, computeReleasedAntsPerPath(costs, releases)
+	}
+	return paths, ComputeAntsPerPath(costs, totalAnts)
+}
+
+// groupPaths returns the paths a group of ants may use, keeping their order.
+// When none of the paths fits, the group falls back to its BFS shortest path,
+// so every group can be scheduled.
+func groupPaths(paths [][]int, group antGroup) [][]int {
+	if group.start == "" && group.end == "" {
+		return paths
+	}
+
+	var own [][]int
+	for _, path := range paths {
+		if group.uses(path) {
+			own = append(own, path)
+		}
+	}
+	if len(own) > 0 {
+		return own
+	}
 
-	// 4) hand off to simulateAnts (now with quotas)
-	simulateAnts(paths, antsPerPath)
+	from, to := graph.Starts, graph.Ends
+	if group.start != "" {
+		from = []int{graph.IDs[group.start]}
+	}
+	if group.end != "" {
+		to = []int{graph.IDs[group.end]}
+	}
+	if path := graph.shortestPath(from, to); path != nil {
+		return [][]int{path}
+	}
+	return nil
 }
 
 // Ant represents the state of an ant in the simulation.
@@ -41,30 +202,42 @@ type Ant struct {
 	ID    int // Ant identifier
 	Path  int // Index into the paths slice (which path the ant is following)
 	Index int // Current index (position) on that path
+	Goal  int // The end room the ant has to reach, the last room of its path
+}
+
+// antMove is a single move of a turn: ant Ant goes from room From to Room.
+type antMove struct {
+	Ant  int
+	From int
+	Room int
 }
 
 // moveAntsInTransit processes ants in transit so that their current room is freed
 // immediately as they start to move. It returns the updated list of ants still in transit
-// along with the movement commands for this turn.
-func moveAntsInTransit(antsInTransit []Ant, paths [][]string, occupied map[string]bool) ([]Ant, []string) {
-	output := []string{}
+// along with the movements for this turn.
+func moveAntsInTransit(antsInTransit []Ant, paths [][]int, occupied []bool) ([]Ant, []antMove) {
+	output := []antMove{}
 	newTransit := []Ant{}
 
-	// Process ants in reverse order so that ants closer to the end are processed first.
-	for i := len(antsInTransit) - 1; i >= 0; i-- {
-		ant := antsInTransit[i]
+	// Process the ants closest to the end of their path first, so the rooms
+	// they leave are free for the ants behind them.
+	sort.SliceStable(antsInTransit, func(i, j int) bool {
+		left := len(paths[antsInTransit[i].Path]) - antsInTransit[i].Index
+		right := len(paths[antsInTransit[j].Path]) - antsInTransit[j].Index
+		return left < right
+	})
+
+	for _, ant := range antsInTransit {
 		path := paths[ant.Path]
 		currentRoom := path[ant.Index]
 		nextIndex := ant.Index + 1
 
 		// Free the current room immediately, since the ant is going to try to leave.
-		delete(occupied, currentRoom)
+		occupied[currentRoom] = false
 
-		// Check if there is a valid next room and if it is not occupied.
 		// Check if there is a next room and if it is not occupied.
 		if nextIndex < len(path) && !occupied[path[nextIndex]] {
-			move := fmt.Sprintf("L%d-%s", ant.ID, path[nextIndex])
-			output = append(output, move)
+			output = append(output, antMove{Ant: ant.ID, From: currentRoom, Room: path[nextIndex]})
 			ant.Index = nextIndex
 
 			// If this ant has not yet reached the final room, add it back to transit.
@@ -75,7 +248,7 @@ func moveAntsInTransit(antsInTransit []Ant, paths [][]string, occupied map[strin
 			// If the ant reaches the final room, do not add it back.
 		} else {
 			// If the ant couldn’t move, re-reserve its current room and keep it in transit.
-			if currentRoom != endRoom {
+			if currentRoom != ant.Goal {
 				occupied[currentRoom] = true
 				newTransit = append(newTransit, ant)
 			}
@@ -88,123 +261,205 @@ func moveAntsInTransit(antsInTransit []Ant, paths [][]string, occupied map[strin
 // simulateAnts is the main simulation function. It repeatedly:
 //  1. Moves ants already in transit,
 //  2. Spawns new ants,
-//  3. Prints all moves for that turn,
-//  4. And finally updates the turn count.
+//  3. Collects all moves for that turn.
 //
-// The simulation stops when no moves occur on a turn.
-func simulateAnts(paths [][]string, quota []int) {
+// Every ant is done once it reaches the goal at the end of its path, which
+// is its own end room when the ants come in groups.
+// The simulation stops when no moves occur on a turn. It only reads the
+// farm, so several simulations can run at the same time.
+func simulateAnts(paths [][]int, quota []int) [][]antMove {
 	if len(paths) == 0 {
 		Log("no valid paths to simulate.", "error")
-		return
+		return nil
 	}
 
+	sim := newSimulation(paths, quota)
+	for sim.step() {
+	}
+	return sim.turns
+}
+
+// simulation is the state of simulateAnts between two turns, so the
+// interactive mode can play it one turn at a time.
+type simulation struct {
+	paths [][]int
+	quota []int
+
 	// These values manage the ant simulation state.
-	antsInTransit := []Ant{}
-	spawned := make([]int, len(paths))
-	nextAnt := 1
-	turns := 0
-
-	// occupied tracks the rooms in use during the current turn.
-	occupied := make(map[string]bool)
-
-	// The simulation loop runs until no moves are produced.
-	for {
-
-		turnOutput := []string{}
-
-		// Move ants already in transit.
-		var moves []string
-		antsInTransit, moves = moveAntsInTransit(antsInTransit, paths, occupied)
-		turnOutput = append(turnOutput, moves...)
-
-		// spawn according to quota
-		for i := range paths {
-			room := paths[i][1] // the first room after start
-			canSpawn := spawned[i] < quota[i]
-			// if it's not the end, also require that it's free
-			if room != endRoom {
-				canSpawn = canSpawn && !occupied[room]
-			}
-			if !canSpawn {
-				continue
-			}
+	antsInTransit []Ant
+	spawned       []int // ants sent down each path so far
+	nextAnt       int
+	turns         [][]antMove
+
+	// occupied tracks the rooms holding an ant. It carries over between turns:
+	// an ant that has not moved yet this turn still blocks its room.
+	occupied []bool
+}
+
+func newSimulation(paths [][]int, quota []int) *simulation {
+	return &simulation{
+		paths:         paths,
+		quota:         quota,
+		antsInTransit: []Ant{},
+		spawned:       make([]int, len(paths)),
+		nextAnt:       1,
+		turns:         [][]antMove{},
+		occupied:      make([]bool, len(graph.Names)),
+	}
+}
 
-			// 1) mark that we've spawned one more on this path
-			spawned[i]++
+// step plays one turn and adds it to turns. It returns false, without adding
+// a turn, once the simulation is complete.
+func (s *simulation) step() bool {
+	turnOutput := []antMove{}
+
+	// Move ants already in transit.
+	var moves []antMove
+	s.antsInTransit, moves = moveAntsInTransit(s.antsInTransit, s.paths, s.occupied)
+	turnOutput = append(turnOutput, moves...)
+
+	// spawn according to quota, as long as released ants are waiting
+	released := releasedBy(len(s.turns) + 1)
+	direct := make(map[[2]int]bool) // start -> end tunnels used this turn
+	for i, path := range s.paths {
+		room := path[1] // the first room after start
+		goal := path[len(path)-1]
+		canSpawn := s.spawned[i] < s.quota[i] && s.nextAnt <= released
+		// if it's not the goal, also require that it's free, and if it is,
+		// that no other path sent an ant through the same tunnel (several
+		// groups may share it)
+		if room != goal {
+			canSpawn = canSpawn && !s.occupied[room]
+		} else {
+			canSpawn = canSpawn && !direct[[2]int{path[0], room}]
+		}
+		if !canSpawn {
+			continue
+		}
+		if room == goal {
+			direct[[2]int{path[0], room}] = true
+		}
 
-			// 2) enqueue the ant so it moves in the next phase
-			antsInTransit = append(antsInTransit, Ant{
-				ID:    nextAnt,
-				Path:  i,
-				Index: 1,
-			})
+		// 1) mark that we've spawned one more on this path
+		s.spawned[i]++
 
-			// 3) record the move (to be printed or turned into JSON)
-			move := fmt.Sprintf("L%d-%s", nextAnt, room)
-			turnOutput = append(turnOutput, move)
+		// 2) enqueue the ant so it moves in the next phase
+		s.antsInTransit = append(s.antsInTransit, Ant{
+			ID:    s.nextAnt,
+			Path:  i,
+			Index: 1,
+			Goal:  goal,
+		})
 
-			// 4) reserve the room **only if** it's not the end
-			if room != endRoom {
-				occupied[room] = true
-			}
+		// 3) record the move (to be printed or turned into JSON)
+		turnOutput = append(turnOutput, antMove{Ant: s.nextAnt, From: path[0], Room: room})
 
-			// 5) bump the ant ID counter
-			nextAnt++
+		// 4) reserve the room **only if** it's not the goal
+		if room != goal {
+			s.occupied[room] = true
 		}
 
-		// If no moves were made this turn, the simulation is complete.
-		if len(turnOutput) == 0 {
-			break
-		}
+		// 5) bump the ant ID counter
+		s.nextAnt++
+	}
 
-		// Sort moves by ant ID for consistent ordering in output.
-		sort.Slice(turnOutput, func(i, j int) bool {
-			var id1, id2 int
-			fmt.Sscanf(turnOutput[i], "L%d-", &id1)
-			fmt.Sscanf(turnOutput[j], "L%d-", &id2)
-			return id1 < id2
-		})
+	// If no moves were made this turn, the simulation is complete,
+	// unless every released ant is out and the next batch is still to come.
+	if len(turnOutput) == 0 && (s.nextAnt <= released || released == ants) {
+		return false
+	}
+
+	// Sort moves by ant ID for consistent ordering in output.
+	sort.Slice(turnOutput, func(i, j int) bool {
+		return turnOutput[i].Ant < turnOutput[j].Ant
+	})
+	s.turns = append(s.turns, turnOutput)
+	return true
+}
+
+// printTurns prints every turn of a finished simulation, or records the moves
+// for the JSON dump in visualizer mode. Room IDs are only turned back into
+// names here.
+func printTurns(turns [][]antMove) {
+	for turn, turnOutput := range turns {
 		if visualizer {
-			// For every move string, parse it and append to allMoves.
-			for _, str := range turnOutput {
-				antID, toRoom := parseMove(str)
-				fromRoom := lastRoom[antID] // lookup where it was
+			for _, m := range turnOutput {
 				allMoves = append(allMoves, Move{
-					Turn: turns + 1,
-					Ant:  antID,
-					From: fromRoom,
-					To:   toRoom,
+					Turn: turn + 1,
+					Ant:  m.Ant,
+					From: graph.Names[m.From],
+					To:   graph.Names[m.Room],
 				})
-				lastRoom[antID] = toRoom // update for next time
 			}
 		} else {
 			// Legacy behavior
-			fmt.Println(strings.Join(turnOutput, " "))
+			if showSpawns {
+				fmt.Println(formatSpawnTurn(turnOutput))
+			} else {
+				fmt.Println(formatTurn(turnOutput))
+			}
 		}
+	}
 
-		turns++
+	// Log the total number of turns (only count turns in which moves were executed).
+	Log(fmt.Sprintf("Total number of turns: %d\n", len(turns)), "debug")
+	Log(fmt.Sprintf("Total moves: %d, sum of arrival turns: %d", countMoves(turns), arrivalSum(turns)), "debug")
+}
 
-		// Reset the occupied map for the next turn.
-		occupied = make(map[string]bool)
+// formatTurn renders the moves of one turn as "L<antID>-<roomName>" entries.
+func formatTurn(turnOutput []antMove) string {
+	moves := make([]string, len(turnOutput))
+	for i, m := range turnOutput {
+		moves[i] = fmt.Sprintf("L%d-%s", m.Ant, graph.Names[m.Room])
+	}
+	return strings.Join(moves, " ")
+}
 
+// formatSpawnTurn is formatTurn naming the start room in the first move of
+// every ant, "L<antID>@<start>-<roomName>", for --show-spawns.
+func formatSpawnTurn(turnOutput []antMove) string {
+	moves := make([]string, len(turnOutput))
+	for i, m := range turnOutput {
+		if graph.IsStart[m.From] {
+			moves[i] = fmt.Sprintf("L%d@%s-%s", m.Ant, graph.Names[m.From], graph.Names[m.Room])
+		} else {
+			moves[i] = fmt.Sprintf("L%d-%s", m.Ant, graph.Names[m.Room])
+		}
 	}
+	return strings.Join(moves, " ")
+}
 
-	// Log the total number of turns (only count turns in which moves were executed).
-	Log(fmt.Sprintf("Total number of turns: %d\n", turns), "debug")
-}
-
-// parseMove takes a string of the form "L<antID>-<roomName>"
-// and returns the integer antID and the roomName.
-func parseMove(s string) (antID int, room string) {
-	// Split on the first dash into ["L3", "h"]
-	parts := strings.SplitN(s, "-", 2)
-	if len(parts) != 2 {
-		// malformed; you could choose to log or panic here instead
-		return 0, ""
-	}
-	// Parse the ant number from the "L3" piece
-	fmt.Sscanf(parts[0], "L%d", &antID)
-	// The second part is the room name
-	room = parts[1]
-	return
+// delivered returns how many ants reached an end room.
+func delivered(turns [][]antMove) int {
+	total := 0
+	for _, turnOutput := range turns {
+		for _, m := range turnOutput {
+			if graph.IsEnd[m.Room] {
+				total++
+			}
+		}
+	}
+	return total
+}
+
+// countMoves returns the total number of ant moves over all turns.
+func countMoves(turns [][]antMove) int {
+	total := 0
+	for _, turnOutput := range turns {
+		total += len(turnOutput)
+	}
+	return total
+}
+
+// arrivalSum adds up the turn every ant reached an end room.
+func arrivalSum(turns [][]antMove) int {
+	total := 0
+	for turn, turnOutput := range turns {
+		for _, m := range turnOutput {
+			if graph.IsEnd[m.Room] {
+				total += turn + 1
+			}
+		}
+	}
+	return total
 }
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// strategyResult is the simulated outcome of one strategy in the portfolio.
+type strategyResult struct {
+	name     string
+	paths    [][]int
+	quota    []int
+	turns    [][]antMove
+	moves    int
+	arrivals int // sum of the arrival turns
+	elapsed  time.Duration
+}
+
+// runPortfolio runs every registered strategy in its own goroutine, simulates
+// the paths each one picked and returns the schedule with the fewest turns,
+// with the paths it uses and the ants each of them carries.
+// Ties go to the schedule with the fewest moves. Strategies share ctx; when it
+// has no deadline of its own the portfolio applies portfolioTimeout.
+func runPortfolio(ctx context.Context) ([][]int, []int, [][]antMove) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, portfolioTimeout)
+		defer cancel()
+	}
+
+	results := make([]strategyResult, len(strategyOrder))
+	var wg sync.WaitGroup
+	for i, name := range strategyOrder {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+
+			result := strategyResult{name: name}
+			if selected := strategies[name](ctx); len(selected) > 0 {
+				paths, antsPerPath := planPaths(selected)
+				result.paths, result.quota = paths, antsPerPath
+				result.turns = simulateAnts(paths, antsPerPath)
+				result.moves = countMoves(result.turns)
+				result.arrivals = arrivalSum(result.turns)
+				if delivered(result.turns) < ants {
+					result.turns = nil // some ants got stuck, not a schedule
+				}
+			}
+			result.elapsed = time.Since(start)
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	best := -1
+	for i, result := range results {
+		if result.turns == nil {
+			Log(fmt.Sprintf("strategy %s: no schedule (%s)", result.name, result.elapsed), "info")
+			continue
+		}
+		Log(fmt.Sprintf("strategy %s: %d turns, %d moves, arrivals adding up to %d (%s)",
+			result.name, len(result.turns), result.moves, result.arrivals, result.elapsed), "info")
+
+		if best == -1 || betterResult(result, results[best]) {
+			best = i
+		}
+	}
+
+	if best == -1 {
+		Log("no strategy produced a schedule", "error")
+		return nil, nil, nil
+	}
+	if expired(ctx) {
+		Log("time budget ran out before every strategy finished, the result may be suboptimal", "info")
+	}
+	Log("using strategy "+results[best].name, "info")
+	return results[best].paths, results[best].quota, results[best].turns
+}
+
+// betterResult prefers the fewest turns, then the --objective metric, the
+// total moves by default.
+func betterResult(a, b strategyResult) bool {
+	if len(a.turns) != len(b.turns) {
+		return len(a.turns) < len(b.turns)
+	}
+	if objective == "arrivals" && a.arrivals != b.arrivals {
+		return a.arrivals < b.arrivals
+	}
+	if a.moves != b.moves {
+		return a.moves < b.moves
+	}
+	return a.arrivals < b.arrivals
+}
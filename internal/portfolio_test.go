@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestPortfolio runs every strategy on tests/test2.txt, where they disagree,
+// and checks the portfolio keeps the fastest schedule.
+func TestPortfolio(t *testing.T) {
+	loadFarm(t, filepath.Join("..", "tests", "test2.txt"))
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(context.Background())
+	FindBestPaths(context.Background())
+
+	fewest := 0
+	disagree := false
+	for _, name := range strategyOrder {
+		turns := len(simulateAnts(planPaths(strategies[name](context.Background()))))
+		if fewest != 0 && turns != fewest {
+			disagree = true
+		}
+		if fewest == 0 || turns < fewest {
+			fewest = turns
+		}
+	}
+	if !disagree {
+		t.Fatal("the strategies agree, the farm does not test the choice")
+	}
+
+	_, _, turns := runPortfolio(context.Background())
+	if len(turns) != fewest {
+		t.Errorf("portfolio took %d turns, the best strategy %d", len(turns), fewest)
+	}
+	if delivered(turns) != ants {
+		t.Errorf("%d of %d ants delivered", delivered(turns), ants)
+	}
+}
+
+func TestBetterResult(t *testing.T) {
+	result := func(turns, moves, arrivals int) strategyResult {
+		return strategyResult{turns: make([][]antMove, turns), moves: moves, arrivals: arrivals}
+	}
+	for _, tc := range []struct {
+		objective string
+		a, b      strategyResult
+		want      bool
+	}{
+		{"", result(8, 30, 50), result(10, 20, 40), true}, // fewer turns first
+		{"", result(8, 20, 50), result(8, 30, 40), true},  // then fewer moves
+		{"", result(8, 20, 50), result(8, 20, 40), false}, // then earlier arrivals
+		{"arrivals", result(8, 30, 40), result(8, 20, 50), true},
+		{"moves", result(8, 30, 40), result(8, 20, 50), false},
+		{"", result(8, 20, 40), result(8, 20, 40), false}, // a tie keeps the first one
+	} {
+		objective = tc.objective
+		if got := betterResult(tc.a, tc.b); got != tc.want {
+			t.Errorf("objective %q: %+v over %+v: got %v", tc.objective, tc.a, tc.b, got)
+		}
+	}
+	objective = ""
+}
+
+// TestPortfolioExpired runs the strategies with the budget already spent: the
+// ones selecting from allPaths stop at once with the shortest path, and the
+// portfolio still delivers every ant.
+func TestPortfolioExpired(t *testing.T) {
+	loadFarm(t, filepath.Join("..", "tests", "test2.txt"))
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, name := range []string{"steps", "disjoint"} {
+		if got := strategies[name](ctx); len(got) != 1 || len(got[0]) != len(shortestOf(allPaths)) {
+			t.Errorf("strategy %s: got %v", name, got)
+		}
+	}
+	if _, _, turns := runPortfolio(ctx); delivered(turns) != ants {
+		t.Errorf("%d of %d ants delivered", delivered(turns), ants)
+	}
+}
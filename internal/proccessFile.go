@@ -1,78 +1,123 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
-func processLine(line string, numLine int) bool {
+// processLine reads one line of the farm file into the package state. It
+// returns nil for lines it accepts or ignores.
+func processLine(line string, numLine int) error {
+	if strict {
+		if err := checkGrammar(line, numLine); err != nil {
+			return err
+		}
+	}
+
 	// validating ants
 	if numLine == 1 { // first line always number of ants
 		antsNumber, err := strconv.Atoi(line)
 		if err != nil {
-			Log("number of ants must be a digit", "error")
-			return false
+			return errors.New("number of ants must be a digit")
 		}
 		if antsNumber <= 0 {
-			Log("number of ants must be > 0", "error")
-			return false
+			return errors.New("number of ants must be > 0")
 		}
 		ants = antsNumber
 		Log(fmt.Sprintf("Number of ants: %d", antsNumber), "debug")
-		return true
+		return nil
 	}
 
-	//validating rooms
-	if strings.HasPrefix(line, "##start") {
-		if startRoomFound == false { // used to enter only once
-			expectingStartRoom = true
-			startRoomFound = true
-			return true
-		} else {
-			Log("Found more than one start rooms", "error")
-			return false
-		}
+	// "##name args" lines go to the registered directives, ##start and ##end
+	// included, see directives.go
+	if strings.HasPrefix(line, "##") {
+		return runDirective(line)
 	}
 
-	if strings.HasPrefix(line, "##end") {
-		if endRoomFound == false {
-			expectingEndRoom = true
-			endRoomFound = true
-			return true
-		} else {
-			Log("Found more than one end rooms", "error")
-			return false
-		}
+	// "#group <ants> <end> [start]" sends that many ants to one end room
+	if strings.HasPrefix(line, "#group ") {
+		return getGroup(line)
+	}
+
+	// "#release 5@0 10@3" makes the ants available over time
+	if strings.HasPrefix(line, "#release ") {
+		return getRelease(line)
 	}
 
 	if isRoomLine(line) {
-		ok := getRoom(line) // create the room
-		if ok {
-			return true
-		}
-		return false
+		return getRoom(line) // create the room
+	}
+
+	// "name" or "name - -" is a room for --layout auto to place
+	if layout == "auto" && isUnplacedRoomLine(line) {
+		return getRoom(line)
 	}
 
 	//validating tunels
 	if isTunnelLine(line) {
-		ok := getTunnel(line) // link the rooms
-		if ok {
-			return true
+		tunnelsStarted = true
+		return getTunnel(line) // link the rooms
+	}
+
+	return nil // unkown comments will be ignored
+}
+
+// checkGrammar holds a line to the canonical lem-in grammar under --strict:
+// the ants, then the rooms, then the tunnels, one line each, with ##start and
+// ##end right before their room and nothing else but comments. By default
+// processLine lets all of that go.
+func checkGrammar(line string, numLine int) error {
+	if line == "" {
+		return errors.New("empty line")
+	}
+	if line != strings.Join(strings.Fields(line), " ") {
+		return errors.New("extra whitespace: " + strconv.Quote(line))
+	}
+	if numLine == 1 {
+		if strings.Trim(line, "0123456789") != "" {
+			return errors.New("number of ants must be a digit")
 		}
-		return false
+		return nil
 	}
 
-	return true // unkown comments will be ignored
+	if expectingStartRoom && !isRoomLine(line) {
+		return errors.New("##start must be followed by a room: " + line)
+	}
+	if expectingEndRoom && !isRoomLine(line) {
+		return errors.New("##end must be followed by a room: " + line)
+	}
+
+	switch {
+	case strings.HasPrefix(line, "##"):
+		if command := strings.Fields(line)[0]; directives[strings.TrimPrefix(command, "##")] == nil {
+			return errors.New("unknown command: " + command)
+		}
+	case strings.HasPrefix(line, "#"):
+		// comments, #group and #release
+	case isRoomLine(line):
+		if tunnelsStarted {
+			return errors.New("room after the tunnels: " + line)
+		}
+	case !isTunnelLine(line):
+		return errors.New("neither a room, a tunnel nor a comment: " + line)
+	}
+	return nil
 }
 
 // Tunnel Functions----------------------------------------------------
 
-func getTunnel(line string) bool {
-	parts := strings.Split(line, "-")
+func getTunnel(line string) error {
+	separator := "-"
+	if strings.Contains(line, ">") {
+		separator = ">" // one-way tunnel a>b, ants can only go from a to b
+	}
+	oneWayTunnel := separator == ">"
+
+	parts := strings.Split(line, separator)
 	if len(parts) != 2 {
-		Log("invalid tunnel format: "+line, "error")
-		return false
+		return errors.New("invalid tunnel format: " + line)
 	}
 
 	a := strings.TrimSpace(parts[0])
@@ -80,38 +125,46 @@ func getTunnel(line string) bool {
 
 	// Validate both rooms exist
 	if _, ok := rooms[a]; !ok {
-		Log("unknown room in tunnel: "+a, "error")
-		return false
+		return errors.New("unknown room in tunnel: " + a)
 	}
 
 	if _, ok := rooms[b]; !ok {
-		Log("unknown room in tunnel: "+b, "error")
-		return false
+		return errors.New("unknown room in tunnel: " + b)
 	}
 
 	if a == b {
-		Log("invalid tunnel: self-link on "+a, "error")
-		return false
+		return errors.New("invalid tunnel: self-link on " + a)
 	}
 
 	// Check for duplicate
-	if isConnected(a, b) {
-		Log("duplicate tunnel between "+a+" and "+b, "error")
-		return false
+	if isConnected(a, b, oneWayTunnel) {
+		return errors.New("duplicate tunnel between " + a + " and " + b)
+	}
+
+	if oneWayTunnel {
+		tunnels[a] = append(tunnels[a], b)
+		oneWay[[2]string{a, b}] = true
+		return annotateTunnel(a, b)
 	}
 
 	// Add bidirectional link
 	tunnels[a] = append(tunnels[a], b)
 	tunnels[b] = append(tunnels[b], a)
-	return true
+	return annotateTunnel(a, b)
 }
 
-func isConnected(a, b string) bool {
+// isConnected reports whether a new tunnel between a and b would duplicate an
+// existing one. A one-way tunnel only clashes with a way from a to b, so a>b
+// and b>a can both exist, while a two-way tunnel clashes with either direction.
+func isConnected(a, b string, oneWayTunnel bool) bool {
 	for _, neighbor := range tunnels[a] {
 		if neighbor == b {
 			return true
 		}
 	}
+	if oneWayTunnel {
+		return false
+	}
 	for _, neighbor := range tunnels[b] {
 		if neighbor == a {
 			return true
@@ -121,50 +174,122 @@ func isConnected(a, b string) bool {
 }
 
 func isTunnelLine(line string) bool {
-	return strings.Count(line, "-") == 1 && !strings.HasPrefix(line, "#")
+	return strings.Count(line, "-")+strings.Count(line, ">") == 1 && !strings.HasPrefix(line, "#")
+}
+
+// Group Functions-----------------------------------------------------
+
+func getGroup(line string) error {
+	parts := strings.Fields(strings.TrimPrefix(line, "#group"))
+	if len(parts) < 2 || len(parts) > 3 {
+		return errors.New("invalid group format: " + line)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n <= 0 {
+		return errors.New("invalid ant count for group: " + line)
+	}
+
+	group := antGroup{ants: n, end: parts[1]}
+	if len(parts) == 3 {
+		group.start = parts[2]
+	}
+	groupLines = append(groupLines, group) // the rooms may come later in the file
+	return nil
 }
 
 // Room Functions------------------------------------------------------
 
-func getRoom(line string) bool { // create room
-	parts := strings.Fields(line) // getRoom assumes the line has already passed isRoomLine validation
+func getRoom(line string) error { // create room
+	parts := strings.Fields(line) // getRoom assumes the line has already passed isRoomLine or isUnplacedRoomLine validation
 	name := parts[0]
-	x, err1 := strconv.Atoi(parts[1])
-	y, err2 := strconv.Atoi(parts[2])
-
-	if err1 != nil || err2 != nil {
-		Log("invalid coordinates for room: "+line, "error")
-		return false
+	room := Room{Name: name, Unplaced: isUnplacedRoomLine(line)}
+	if !room.Unplaced {
+		x, err1 := strconv.Atoi(parts[1])
+		y, err2 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil {
+			return errors.New("invalid coordinates for room: " + line)
+		}
+		room.X, room.Y = x, y
 	}
 
 	if _, exists := rooms[name]; exists {
-		Log("duplicate room name: "+name, "error")
-		return false
+		return errors.New("duplicate room name: " + name)
 	}
 
 	if strings.HasPrefix(name, "L") || strings.HasPrefix(name, "#") {
-		Log("invalid room name: "+name, "error")
-		return false
+		return errors.New("invalid room name: " + name)
 	}
 
-	room := Room{Name: name, X: x, Y: y}
 	rooms[name] = room
 
+	if expectingStartRoom && expectingEndRoom {
+		return errors.New("room is both a start and an end room: " + name)
+	}
+
 	if expectingStartRoom {
-		startRoom = name
+		if startRoom == "" {
+			startRoom = name
+		}
+		startRooms = append(startRooms, name)
+		if pendingStartAnts > 0 {
+			startAnts[name] = pendingStartAnts
+		}
 		expectingStartRoom = false
 	}
 
 	if expectingEndRoom {
-		endRoom = name
+		if endRoom == "" {
+			endRoom = name
+		}
+		endRooms = append(endRooms, name)
 		expectingEndRoom = false
 	}
-	return true
+	return annotateRoom(name)
+}
+
+// isStartRoom reports whether name is one of the start rooms.
+func isStartRoom(name string) bool {
+	for _, room := range startRooms {
+		if room == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isEndRoom reports whether name is one of the end rooms.
+func isEndRoom(name string) bool {
+	for _, room := range endRooms {
+		if room == name {
+			return true
+		}
+	}
+	return false
 }
 
 func isRoomLine(line string) bool {
 	parts := strings.Fields(line)
-	return len(parts) == 3 && !strings.HasPrefix(line, "#") && !strings.Contains(line, "-")
+	if len(parts) != 3 || strings.HasPrefix(line, "#") || strings.ContainsAny(parts[0], "->") {
+		return false
+	}
+	// coordinates may be negative, a lone "-" is still a tunnel
+	for _, coordinate := range parts[1:] {
+		digits := strings.TrimPrefix(coordinate, "-")
+		if digits == "" || strings.ContainsAny(digits, "->") {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnplacedRoomLine reports whether line is a room without coordinates,
+// "name" or "name - -".
+func isUnplacedRoomLine(line string) bool {
+	parts := strings.Fields(line)
+	if len(parts) == 0 || strings.HasPrefix(line, "#") || strings.ContainsAny(parts[0], "->") {
+		return false
+	}
+	return len(parts) == 1 || len(parts) == 3 && parts[1] == "-" && parts[2] == "-"
 }
 
 // ------------------------------------------------------
@@ -0,0 +1,308 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// PruneGraph removes the rooms that can never be part of a start -> end path
+// before the search runs:
+//   - dead ends, rooms with no way in, no way out, or a single neighbour,
+//   - rooms that can only be reached by going through a start or end room,
+//   - rooms off the chain of blocks linking the start rooms to the end rooms,
+//     such as a loop hanging off a single room, see offChainRooms.
+//
+// Removing a room can turn its neighbours into dead ends, so the passes repeat
+// until nothing changes. Pruned rooms stay in rooms (the visualizer still draws
+// them), only their tunnels are dropped. The exporters still draw those from
+// inputTunnels.
+func PruneGraph() {
+	removed := make(map[string]bool)
+	tunnelsBefore := countTunnels()
+
+	inputTunnels = make(map[string][]string, len(tunnels))
+	for room, neighbors := range tunnels {
+		inputTunnels[room] = append([]string(nil), neighbors...)
+	}
+
+	// incoming[b] lists the rooms with a tunnel leading into b
+	incoming := make(map[string][]string)
+	for room, neighbors := range tunnels {
+		for _, neighbor := range neighbors {
+			incoming[neighbor] = append(incoming[neighbor], room)
+		}
+	}
+
+	remove := func(room string) {
+		for _, neighbor := range tunnels[room] {
+			incoming[neighbor] = withoutRoom(incoming[neighbor], room)
+		}
+		for _, neighbor := range incoming[room] {
+			tunnels[neighbor] = withoutRoom(tunnels[neighbor], room)
+		}
+		delete(tunnels, room)
+		delete(incoming, room)
+		removed[room] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		// dead ends
+		for room := range rooms {
+			if !isStartRoom(room) && !isEndRoom(room) && !removed[room] && isDeadEnd(tunnels[room], incoming[room]) {
+				remove(room)
+				changed = true
+			}
+		}
+
+		// a simple path visits start and end once, so rooms behind them are useless
+		fromStart := bfsDistances(startRooms, isEndRoom, tunnels)
+		toEnd := bfsDistances(endRooms, isStartRoom, incoming)
+		for room := range rooms {
+			if isStartRoom(room) || isEndRoom(room) || removed[room] {
+				continue
+			}
+			_, okStart := fromStart[room]
+			_, okEnd := toEnd[room]
+			if !okStart || !okEnd {
+				remove(room)
+				changed = true
+			}
+		}
+
+		for _, room := range offChainRooms(incoming) {
+			remove(room)
+			changed = true
+		}
+	}
+
+	Log(fmt.Sprintf("Pruned %d rooms and %d tunnels", len(removed), tunnelsBefore-countTunnels()), "info")
+}
+
+// offChainRooms returns the rooms no simple start -> end path can go through.
+// The tunnels are taken both ways and a source linked to every start room and
+// a sink linked to every end room are added. A path from the source to the
+// sink can only use the blocks (biconnected components) on the chain between
+// them: it enters any other block and has to leave it through the same room.
+// Start and end rooms are never returned.
+func offChainRooms(incoming map[string][]string) []string {
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		if len(tunnels[name]) > 0 || len(incoming[name]) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // the same farm is pruned the same way
+	id := make(map[string]int, len(names))
+	for i, name := range names {
+		id[name] = i
+	}
+	source, sink := len(names), len(names)+1
+
+	adjacency := make([][]int, len(names)+2)
+	linked := make(map[[2]int]bool)
+	link := func(a, b int) {
+		if a > b {
+			a, b = b, a
+		}
+		if !linked[[2]int{a, b}] {
+			linked[[2]int{a, b}] = true
+			adjacency[a] = append(adjacency[a], b)
+			adjacency[b] = append(adjacency[b], a)
+		}
+	}
+	for _, name := range names {
+		for _, neighbor := range tunnels[name] {
+			link(id[name], id[neighbor])
+		}
+	}
+	for _, start := range startRooms {
+		if i, ok := id[start]; ok {
+			link(source, i)
+		}
+	}
+	for _, end := range endRooms {
+		if i, ok := id[end]; ok {
+			link(sink, i)
+		}
+	}
+
+	blocks := biconnectedBlocks(adjacency, source)
+
+	// blocks sharing a room are neighbours, the chain is the shortest way
+	// from a block of the source to a block of the sink
+	blocksOf := make([][]int, len(adjacency))
+	for b, block := range blocks {
+		for _, v := range block {
+			blocksOf[v] = append(blocksOf[v], b)
+		}
+	}
+	previous := make([]int, len(blocks))
+	for b := range previous {
+		previous[b] = -2 // not seen
+	}
+	queue := []int{}
+	for _, b := range blocksOf[source] {
+		previous[b] = -1
+		queue = append(queue, b)
+	}
+	last := -1
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		if slices.Contains(blocks[b], sink) {
+			last = b
+			break
+		}
+		for _, v := range blocks[b] {
+			for _, next := range blocksOf[v] {
+				if previous[next] == -2 {
+					previous[next] = b
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	if last == -1 {
+		return nil // no end room is reachable, checkConnectivity reports it
+	}
+
+	onChain := make([]bool, len(adjacency))
+	for b := last; b != -1; b = previous[b] {
+		for _, v := range blocks[b] {
+			onChain[v] = true
+		}
+	}
+	var off []string
+	for i, name := range names {
+		if !onChain[i] && !isStartRoom(name) && !isEndRoom(name) {
+			off = append(off, name)
+		}
+	}
+	return off
+}
+
+// biconnectedBlocks returns the rooms of every block of the undirected graph
+// reachable from root, with Tarjan's algorithm on an explicit stack.
+func biconnectedBlocks(adjacency [][]int, root int) [][]int {
+	type frame struct {
+		v, parent, next int
+	}
+	disc := make([]int, len(adjacency))
+	low := make([]int, len(adjacency))
+	for v := range disc {
+		disc[v] = -1
+	}
+
+	var blocks [][]int
+	var edges [][2]int
+	disc[root], low[root] = 0, 0
+	clock := 1
+	stack := []frame{{v: root, parent: -1}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		v := top.v
+		if top.next < len(adjacency[v]) {
+			w := adjacency[v][top.next]
+			top.next++
+			switch {
+			case disc[w] == -1:
+				edges = append(edges, [2]int{v, w})
+				disc[w], low[w] = clock, clock
+				clock++
+				stack = append(stack, frame{v: w, parent: v})
+			case w != top.parent && disc[w] < disc[v]:
+				edges = append(edges, [2]int{v, w})
+				low[v] = min(low[v], disc[w])
+			}
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		if len(stack) == 0 {
+			break
+		}
+		parent := stack[len(stack)-1].v
+		low[parent] = min(low[parent], low[v])
+		if low[v] >= disc[parent] {
+			// parent separates the block of v from the rest
+			var block []int
+			seen := make(map[int]bool)
+			for {
+				e := edges[len(edges)-1]
+				edges = edges[:len(edges)-1]
+				for _, u := range e {
+					if !seen[u] {
+						seen[u] = true
+						block = append(block, u)
+					}
+				}
+				if e == [2]int{parent, v} {
+					break
+				}
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// isDeadEnd reports whether a room with the given outgoing and incoming
+// tunnels can be passed through: it needs a way in and a way out that do not
+// both lead to the same single neighbour.
+func isDeadEnd(out, in []string) bool {
+	if len(out) == 0 || len(in) == 0 {
+		return true
+	}
+	for _, room := range out {
+		if room != out[0] {
+			return false
+		}
+	}
+	for _, room := range in {
+		if room != out[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// bfsDistances returns the number of steps from the nearest of the given
+// rooms to every room it can reach through adjacency without going through
+// the rooms avoid reports.
+func bfsDistances(from []string, avoid func(string) bool, adjacency map[string][]string) map[string]int {
+	dist := make(map[string]int)
+	queue := []string{}
+	for _, room := range from {
+		dist[room] = 0
+		queue = append(queue, room)
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if avoid(current) {
+			continue // reached, but not walked through
+		}
+
+		for _, neighbor := range adjacency[current] {
+			if _, seen := dist[neighbor]; !seen {
+				dist[neighbor] = dist[current] + 1
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return dist
+}
+
+func withoutRoom(list []string, room string) []string {
+	kept := list[:0]
+	for _, r := range list {
+		if r != room {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// searchTunnels lists the tunnels left for the search, a-b for a two-way
+// tunnel and a>b for a one-way one.
+func searchTunnels() string {
+	var list []string
+	for room, neighbors := range tunnels {
+		for _, neighbor := range neighbors {
+			switch {
+			case oneWay[[2]string{room, neighbor}]:
+				list = append(list, room+">"+neighbor)
+			case room < neighbor:
+				list = append(list, room+"-"+neighbor)
+			}
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+func TestPruneGraph(t *testing.T) {
+	// s-a-e is the only way through, every farm adds rooms around it
+	const farm = "1\n##start\ns 0 0\na 1 0\n##end\ne 2 0\n"
+	for _, tc := range []struct {
+		name    string
+		extra   string
+		rooms   int // rooms left without a tunnel
+		tunnels int // tunnels dropped
+		want    string
+	}{
+		{"nothing to prune", "b 1 1\ns-a\na-e\ns-b\nb-e\n", 0, 0, "a-e a-s b-e b-s"},
+		{"dead end", "x 1 1\ns-a\na-e\na-x\n", 1, 1, "a-e a-s"},
+		{"dead end chain", "x 1 1\ny 1 2\ns-a\na-e\na-x\nx-y\n", 2, 2, "a-e a-s"},
+		{"loop off a room", "x 1 1\ny 1 2\ns-a\na-e\na-x\na-y\nx-y\n", 2, 3, "a-e a-s"},
+		{"loop off a dead end", "x 1 1\ny 1 2\nz 2 2\ns-a\na-e\na-x\nx-y\ny-z\nz-x\n", 3, 4, "a-e a-s"},
+		{"loop on the way", "b 1 1\nc 1 2\ns-a\na-e\ns-b\nb-c\nc-e\n", 0, 0, "a-e a-s b-c b-s c-e"},
+		{"behind the start room", "b 0 1\nc 0 2\ns-a\na-e\ns-b\ns-c\nb-c\n", 2, 3, "a-e a-s"},
+		{"behind the end room", "f 2 1\ng 2 2\ns-a\na-e\ne-f\ne-g\nf-g\n", 2, 3, "a-e a-s"},
+		{"one-way dead ends", "x 1 1\nz 1 2\ns-a\na-e\na>x\nz>a\n", 2, 2, "a-e a-s"},
+		{"one-way back and forth", "x 1 1\ns-a\na-e\na>x\nx>a\n", 1, 2, "a-e a-s"},
+		{"one-way through", "b 1 1\ns-a\na-e\ns>b\nb>e\n", 0, 0, "a-e a-s b>e s>b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resetFarm()
+			if err := parseFarm(strings.NewReader(farm + tc.extra)); err != nil {
+				t.Fatal(err)
+			}
+			tunnelsBefore := countTunnels()
+			PruneGraph()
+
+			if got := searchTunnels(); got != tc.want {
+				t.Errorf("got tunnels %q, want %q", got, tc.want)
+			}
+			if got := tunnelsBefore - countTunnels(); got != tc.tunnels {
+				t.Errorf("dropped %d tunnels, want %d", got, tc.tunnels)
+			}
+			left := 0
+			for room := range rooms {
+				if !strings.Contains(" "+strings.NewReplacer("-", " ", ">", " ").Replace(tc.want)+" ", " "+room+" ") {
+					left++
+				}
+			}
+			if left != tc.rooms {
+				t.Errorf("%d rooms left without a tunnel, want %d", left, tc.rooms)
+			}
+		})
+	}
+}
+
+func TestPathCaps(t *testing.T) {
+	defer func() { maxPaths, maxPathLen = 0, -1 }()
+	search := func() [][]int {
+		loadFarm(t, writeLadderFarm(t, 4, 6, 10))
+		PruneGraph()
+		BuildGraph()
+		FindAllPaths(context.Background())
+		return allPaths
+	}
+
+	all := search()
+	shortest := len(all[0])
+	for _, path := range all {
+		shortest = min(shortest, len(path))
+	}
+
+	maxPaths = 3
+	if got := search(); len(got) != 3 || len(all) <= 3 {
+		t.Errorf("--max-paths 3: got %d paths out of %d", len(got), len(all))
+	}
+	maxPaths = 0
+
+	// the paths crossing a rung are one step longer than the lanes
+	maxPathLen = 0
+	got := search()
+	if len(got) == 0 || len(got) >= len(all) {
+		t.Errorf("--max-path-len 0: got %d paths out of %d", len(got), len(all))
+	}
+	if i := slices.IndexFunc(got, func(p []int) bool { return len(p) > shortest }); i != -1 {
+		t.Errorf("--max-path-len 0: path %v is longer than %d rooms", got[i], shortest)
+	}
+}
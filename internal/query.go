@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The reverse queries answer "how many ants within T turns" and "how many
+// turns for N ants" from path lengths alone, without simulating a move. Every
+// ant may take every path, so farms with ant groups, per-start ant counts or
+// a release schedule are refused, see errFixedAnts.
+
+// errFixedAnts refuses to change the ant count of a farm whose ants are not
+// interchangeable, for the queries and the sweep.
+var errFixedAnts = errors.New("cannot change the ant count of a farm with ant groups, per-start ant counts or a release schedule")
+
+// fixedAnts tells whether the ants of the farm are split into groups, by
+// #group lines or "##start <ants>", or released over time.
+func fixedAnts() bool {
+	return len(antGroups) > 1 || antGroups[0].start != "" || antGroups[0].end != "" || releases != nil
+}
+
+var (
+	antsWithinQuery = 0 // --ants-within: turn budget to fill with as many ants as possible
+	turnsForQuery   = 0 // --turns-for: ant count to deliver as fast as possible
+)
+
+// MaxAnts parses a farm and returns how many ants could reach an end room
+// within turns turns. The ant count of the farm is ignored.
+func MaxAnts(ctx context.Context, r io.Reader, turns int) (int, error) {
+	if turns <= 0 {
+		return 0, errors.New("the turn budget must be > 0")
+	}
+	if err := lockSolver(ctx); err != nil {
+		return 0, err
+	}
+	defer unlockSolver()
+	if err := loadForQuery(ctx, r); err != nil {
+		return 0, err
+	}
+	return maxAntsWithin(queryPathSets(ctx), turns), nil
+}
+
+// MinTurns parses a farm and returns the fewest turns n ants need to reach
+// the end rooms. The ant count of the farm is ignored.
+func MinTurns(ctx context.Context, r io.Reader, n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("the ant count must be > 0")
+	}
+	if err := lockSolver(ctx); err != nil {
+		return 0, err
+	}
+	defer unlockSolver()
+	if err := loadForQuery(ctx, r); err != nil {
+		return 0, err
+	}
+	return minTurnsFor(queryPathSets(ctx), n), nil
+}
+
+// loadForQuery runs the pipeline of Solve up to the path search, for a farm
+// whose ant count may change.
+func loadForQuery(ctx context.Context, r io.Reader) error {
+	resetFarm()
+	if err := parseFarm(r); err != nil {
+		return err
+	}
+	if fixedAnts() {
+		return errFixedAnts
+	}
+	if err := checkConnectivity(); err != nil {
+		return err
+	}
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(ctx)
+	return nil
+}
+
+// Query answers --ants-within or --turns-for for the CLI once the paths are
+// found. It returns false when neither was asked, the schedule is wanted.
+func Query(ctx context.Context) bool {
+	if (antsWithinQuery > 0 || turnsForQuery > 0) && fixedAnts() {
+		Log(errFixedAnts.Error(), "error")
+		return true
+	}
+	switch {
+	case antsWithinQuery > 0:
+		n := maxAntsWithin(queryPathSets(ctx), antsWithinQuery)
+		fmt.Printf("%d ants can reach the end within %d turns\n", n, antsWithinQuery)
+	case turnsForQuery > 0:
+		turns := minTurnsFor(queryPathSets(ctx), turnsForQuery)
+		fmt.Printf("%d ants need %d turns\n", turnsForQuery, turns)
+	default:
+		return false
+	}
+	return true
+}
+
+// queryPathSets returns the sets of room-disjoint paths the queries choose
+// from: the selection of every strategy, and every intermediate set of the
+// flow strategy, since fewer but shorter paths win for few ants or few turns.
+// Paths sharing a room cannot each carry an ant per turn, so selections with
+// overlapping paths are left out.
+func queryPathSets(ctx context.Context) [][][]int {
+	var sets [][][]int
+	for _, name := range strategyOrder {
+		if paths := strategies[name](ctx); len(paths) > 0 && roomDisjoint(paths) {
+			sets = append(sets, paths)
+		}
+	}
+
+	net := newFlowNetwork(graph)
+	for !expired(ctx) && net.augment() {
+		sets = append(sets, net.paths())
+	}
+	return sets
+}
+
+// roomDisjoint tells whether no two paths share a room besides the start and
+// end rooms.
+func roomDisjoint(paths [][]int) bool {
+	used := make(map[int]bool)
+	for _, path := range paths {
+		for _, id := range path {
+			if graph.IsStart[id] || graph.IsEnd[id] {
+				continue
+			}
+			if used[id] {
+				return false
+			}
+			used[id] = true
+		}
+	}
+	return true
+}
+
+// pathCosts returns the lengths of paths, in moves.
+func pathCosts(paths [][]int) []int {
+	costs := make([]int, len(paths))
+	for i, p := range paths {
+		costs[i] = len(p) - 1
+	}
+	sort.Ints(costs)
+	return costs
+}
+
+// maxAntsWithin is the most ants one of the sets delivers within turns turns.
+// capacityForTurn counts the turn after the last move, hence turns+1.
+func maxAntsWithin(sets [][][]int, turns int) int {
+	best := 0
+	for _, paths := range sets {
+		best = max(best, capacityForTurn(pathCosts(paths), turns+1))
+	}
+	return best
+}
+
+// minTurnsFor is the fewest turns n ants need on the best of the sets.
+func minTurnsFor(sets [][][]int, n int) int {
+	best := -1
+	for _, paths := range sets {
+		costs := pathCosts(paths)
+		// the shortest path alone takes costs[0]+n-1 turns, a safe upper bound
+		lo, hi := costs[0], costs[0]+n-1
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if capacityForTurn(costs, mid+1) >= n {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		if best == -1 || lo < best {
+			best = lo
+		}
+	}
+	return best
+}
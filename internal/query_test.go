@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReverseQueries(t *testing.T) {
+	for _, tc := range []struct {
+		file         string
+		ants, turns  int
+		within, most int // most ants within turns
+	}{
+		{"test.txt", 4, 6, 6, 4},
+		{"test3.txt", 20, 11, 11, 20},
+		{"test5.txt", 9, 6, 3, 3},
+	} {
+		file, err := os.Open(filepath.Join("..", "tests", tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		turns, err := MinTurns(context.Background(), file, tc.ants)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if turns != tc.turns {
+			t.Errorf("%s: %d ants need %d turns, want %d", tc.file, tc.ants, turns, tc.turns)
+		}
+
+		file.Seek(0, 0)
+		most, err := MaxAnts(context.Background(), file, tc.within)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if most != tc.most {
+			t.Errorf("%s: %d ants within %d turns, want %d", tc.file, most, tc.within, tc.most)
+		}
+	}
+}
+
+// TestReverseQueriesAgree checks that both queries are the inverse of each
+// other: n ants need T turns exactly when T turns fit n ants and T-1 do not.
+func TestReverseQueriesAgree(t *testing.T) {
+	loadFarm(t, filepath.Join("..", "tests", "test2.txt"))
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(context.Background())
+	sets := queryPathSets(context.Background())
+
+	for n := 1; n <= 50; n++ {
+		turns := minTurnsFor(sets, n)
+		if maxAntsWithin(sets, turns) < n || maxAntsWithin(sets, turns-1) >= n {
+			t.Errorf("%d ants need %d turns, but %d fit in it and %d in one turn less",
+				n, turns, maxAntsWithin(sets, turns), maxAntsWithin(sets, turns-1))
+		}
+	}
+}
+
+// TestReverseQueriesFixedAnts refuses the farms Sweep refuses, their ants
+// cannot take every path at any time.
+func TestReverseQueriesFixedAnts(t *testing.T) {
+	for _, name := range []string{"groups.txt", "multi.txt", "release.txt"} {
+		data, err := os.ReadFile(filepath.Join("..", "tests", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MaxAnts(context.Background(), bytes.NewReader(data), 10); err != errFixedAnts {
+			t.Errorf("%s: MaxAnts got %v", name, err)
+		}
+		if _, err := MinTurns(context.Background(), bytes.NewReader(data), 10); err != errFixedAnts {
+			t.Errorf("%s: MinTurns got %v", name, err)
+		}
+		if _, err := Sweep(context.Background(), bytes.NewReader(data), nil); err != errFixedAnts {
+			t.Errorf("%s: Sweep got %v", name, err)
+		}
+	}
+}
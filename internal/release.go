@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// release is a batch of ants that become available at a turn. Ants released
+// at turn r can make their first move on turn r+1.
+type release struct {
+	ants int
+	turn int
+}
+
+// parseRelease reads a schedule like "5@0 10@3 20@10", batches may also be
+// separated by commas. The batches come back sorted by turn.
+func parseRelease(spec string) ([]release, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return nil, errors.New("empty release schedule")
+	}
+
+	var batches []release
+	for _, field := range fields {
+		n, t, ok := strings.Cut(field, "@")
+		count, err1 := strconv.Atoi(n)
+		turn, err2 := strconv.Atoi(t)
+		if !ok || err1 != nil || err2 != nil || count <= 0 || turn < 0 {
+			return nil, errors.New("invalid release batch " + field + ", want <ants>@<turn>")
+		}
+		batches = append(batches, release{ants: count, turn: turn})
+	}
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].turn < batches[j].turn })
+	return batches, nil
+}
+
+// getRelease reads a "#release" line.
+func getRelease(line string) error {
+	if releaseLine != nil {
+		return errors.New("duplicate release schedule: " + line)
+	}
+	batches, err := parseRelease(strings.TrimPrefix(line, "#release"))
+	if err != nil {
+		return err
+	}
+	releaseLine = batches
+	return nil
+}
+
+// buildReleases picks the release schedule, --release over #release, and
+// checks that it releases every ant. The batches release the ants by number
+// whatever their group, so the quotas could not honour them with several
+// groups: that is refused.
+func buildReleases() error {
+	releases = releaseLine
+	if releaseFlag != nil {
+		releases = releaseFlag
+	}
+	if releases != nil && len(antGroups) > 1 {
+		return errors.New("a release schedule cannot be combined with several ant groups")
+	}
+
+	total := 0
+	for _, batch := range releases {
+		total += batch.ants
+	}
+	if releases != nil && total != ants {
+		return fmt.Errorf("release schedule releases %d ants, not %d", total, ants)
+	}
+	return nil
+}
+
+// releasedBy returns how many ants may have left a start room by the end of
+// turn (turns start at 1).
+func releasedBy(turn int) int {
+	if releases == nil {
+		return ants
+	}
+	n := 0
+	for _, batch := range releases {
+		if batch.turn < turn {
+			n += batch.ants
+		}
+	}
+	return n
+}
@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Result is what one run of the solver produced for a farm.
+type Result struct {
+	Ants          int           `json:"ants"`
+	Rooms         int           `json:"rooms"`
+	Tunnels       int           `json:"tunnels"`
+	Turns         int           `json:"turns"`
+	LowerBound    int           `json:"lower_bound"`
+	Paths         [][]string    `json:"paths"`  // the paths ants were sent through
+	Moves         []string      `json:"moves"`  // the moves of each turn, as printed
+	Spawns        []string      `json:"spawns"` // the start room of every ant, ant n at index n-1, "" if it never left
+	TotalMoves    int           `json:"total_moves"`
+	ArrivalSum    int           `json:"arrival_sum"`              // the arrival turns of all ants added up
+	BaselineTurns int           `json:"baseline_turns,omitempty"` // turns without the --events failures
+	TimedOut      bool          `json:"timed_out"`
+	Elapsed       time.Duration `json:"elapsed_ns"`
+}
+
+// solveLock serializes library calls, they all share the package state. It is
+// a channel rather than a mutex so a caller can stop waiting for it.
+var solveLock = make(chan struct{}, 1)
+
+// lockSolver takes solveLock, or gives up with ctx.Err() when ctx ends first.
+func lockSolver(ctx context.Context) error {
+	select {
+	case solveLock <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case solveLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func unlockSolver() {
+	<-solveLock
+}
+
+// SolveFile runs Solve on a farm file.
+func SolveFile(ctx context.Context, filename string) (*Result, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("failed to open file")
+	}
+	defer file.Close()
+	return Solve(ctx, file)
+}
+
+// Solve runs the whole pipeline of cmd.Cmd on a farm description and returns
+// the schedule instead of printing it. Errors that would make the command exit
+// are returned.
+func Solve(ctx context.Context, r io.Reader) (*Result, error) {
+	if err := lockSolver(ctx); err != nil {
+		return nil, err
+	}
+	defer unlockSolver()
+
+	start := time.Now()
+	resetFarm()
+	if err := parseFarm(r); err != nil {
+		return nil, err
+	}
+	if err := checkConnectivity(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Ants:    ants,
+		Rooms:   len(rooms),
+		Tunnels: countTunnels(),
+	}
+
+	PruneGraph()
+	BuildGraph()
+	FindAllPaths(ctx)
+	FindBestPaths(ctx)
+	paths, turns := schedule(ctx)
+	if turns == nil {
+		return nil, errors.New("no valid paths to simulate.")
+	}
+
+	result.Turns = len(turns)
+	result.LowerBound = lowerBound()
+	for _, path := range paths {
+		result.Paths = append(result.Paths, graph.pathNames(path))
+	}
+	for _, turnOutput := range turns {
+		result.Moves = append(result.Moves, formatTurn(turnOutput))
+	}
+	for _, start := range spawnRooms(turns) {
+		name := "" // the ant never left
+		if start != -1 {
+			name = graph.Names[start]
+		}
+		result.Spawns = append(result.Spawns, name)
+	}
+	result.TotalMoves = countMoves(turns)
+	result.ArrivalSum = arrivalSum(turns)
+	result.BaselineTurns = baselineTurns
+	result.TimedOut = timedOut
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// Farm is a parsed farm, as Validate returns it.
+type Farm struct {
+	Ants     int      `json:"ants"`
+	Rooms    []Room   `json:"rooms"`   // sorted by name
+	Tunnels  []Tunnel `json:"tunnels"` // sorted, two-way tunnels only once
+	Starts   []string `json:"starts"`
+	Ends     []string `json:"ends"`
+	Warnings []string `json:"warnings,omitempty"` // see checkGeometry
+}
+
+// Tunnel links two rooms, one-way tunnels only lead from From to To.
+type Tunnel struct {
+	From   string            `json:"from"`
+	To     string            `json:"to"`
+	OneWay bool              `json:"one_way,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"` // set by directives like ##label, see directives.go
+}
+
+// Validate parses a farm and checks that ants can get from a start room to an
+// end room, without solving it.
+func Validate(r io.Reader) (*Farm, error) {
+	return ValidateContext(context.Background(), r)
+}
+
+// ValidateContext is Validate giving up with ctx.Err() when ctx ends before
+// the farm can be read.
+func ValidateContext(ctx context.Context, r io.Reader) (*Farm, error) {
+	if err := lockSolver(ctx); err != nil {
+		return nil, err
+	}
+	defer unlockSolver()
+
+	resetFarm()
+	if err := parseFarm(r); err != nil {
+		return nil, err
+	}
+	if err := checkConnectivity(); err != nil {
+		return nil, err
+	}
+
+	return farmInfo(), nil
+}
+
+// farmInfo describes the parsed farm.
+func farmInfo() *Farm {
+	return &Farm{
+		Ants:     ants,
+		Rooms:    displayRooms(),
+		Tunnels:  tunnelList(),
+		Starts:   startRooms,
+		Ends:     endRooms,
+		Warnings: warnings,
+	}
+}
+
+// tunnelList returns the tunnels of the input sorted, two-way tunnels only
+// once. Tunnels PruneGraph dropped are in the list.
+func tunnelList() []Tunnel {
+	var list []Tunnel
+	for room, neighbors := range drawnTunnels() {
+		for _, neighbor := range neighbors {
+			tunnel := Tunnel{From: room, To: neighbor, OneWay: oneWay[[2]string{room, neighbor}]}
+			if tunnel.OneWay || room < neighbor { // two-way tunnels are stored both ways
+				tunnel.Meta = tunnelMeta[[2]string{room, neighbor}]
+				list = append(list, tunnel)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return list
+}
+
+// drawnTunnels returns every tunnel of the input, pruned or not, for the
+// exporters to draw.
+func drawnTunnels() map[string][]string {
+	if inputTunnels != nil {
+		return inputTunnels
+	}
+	return tunnels
+}
+
+// countTunnels returns the number of tunnels. Two-way tunnels are stored in
+// both directions, one-way tunnels only once.
+func countTunnels() int {
+	links, oneWayLinks := 0, 0
+	for room, neighbors := range tunnels {
+		for _, neighbor := range neighbors {
+			if oneWay[[2]string{room, neighbor}] {
+				oneWayLinks++
+			} else {
+				links++
+			}
+		}
+	}
+	return links/2 + oneWayLinks
+}
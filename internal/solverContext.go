@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// SolverContext returns the context that bounds the search, limited by the
+// --timeout budget when one was given.
+func SolverContext() (context.Context, context.CancelFunc) {
+	return WithSolverTimeout(context.Background())
+}
+
+// WithSolverTimeout is SolverContext for a search that also ends with parent,
+// such as the request of a server.
+func WithSolverTimeout(parent context.Context) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// expired tells whether the search has to stop: ctx was cancelled or its
+// deadline has passed. The deadline is also checked against the clock, the
+// timer ending ctx cannot fire while the search holds the only thread, as in
+// WebAssembly.
+func expired(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
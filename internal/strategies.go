@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+// Strategy picks the set of paths the ants are sent through. Strategies only
+// read the parsed farm, so the portfolio can run several of them at once.
+type Strategy func(ctx context.Context) [][]int
+
+var (
+	strategies    = make(map[string]Strategy)
+	strategyOrder []string // registration order, keeps the portfolio output stable
+)
+
+// RegisterStrategy makes a path selection strategy available to the portfolio.
+// Registering an existing name replaces it.
+func RegisterStrategy(name string, s Strategy) {
+	if _, exists := strategies[name]; !exists {
+		strategyOrder = append(strategyOrder, name)
+	}
+	strategies[name] = s
+}
+
+func init() {
+	RegisterStrategy("steps", stepsStrategy)
+	RegisterStrategy("disjoint", disjointStrategy)
+	RegisterStrategy("flow", flowStrategy)
+}
+
+// stepsStrategy is the default selection done by FindBestPaths.
+func stepsStrategy(ctx context.Context) [][]int {
+	return selectStepPaths(ctx, allPaths)
+}
+
+// disjointStrategy only keeps paths that share no intermediate room.
+func disjointStrategy(ctx context.Context) [][]int {
+	if expired(ctx) {
+		return [][]int{shortestOf(allPaths)} // no time to sort them
+	}
+	sortedPaths := getSortedPathsBySteps(allPaths)
+	return selectDisjointPaths(ctx, sortedPaths, len(sortedPaths), 0)
+}
+
+// flowStrategy grows a set of room-disjoint paths one augmenting path at a time
+// and keeps the set that needs the fewest turns. Unlike the other strategies it
+// works on the graph directly and does not need allPaths. The first path is
+// always taken, so an expired ctx still yields a schedule.
+func flowStrategy(ctx context.Context) [][]int {
+	net := newFlowNetwork(graph)
+
+	var best [][]int
+	bestTurns := 0
+	for (best == nil || !expired(ctx)) && net.augment() {
+		paths := net.paths()
+		sort.SliceStable(paths, func(i, j int) bool {
+			return len(paths[i]) < len(paths[j])
+		})
+
+		turns := estimateGroupTurns(paths)
+		if best == nil || turns < bestTurns {
+			best, bestTurns = paths, turns
+		}
+	}
+	return best
+}
+
+// flowEdge is one arc of the flow network, rev is the index of its reverse arc.
+type flowEdge struct {
+	to   int
+	rev  int
+	cap  int
+	orig int // capacity before any flow was pushed
+}
+
+// flowNetwork splits every room in two nodes (in = 2*id, out = 2*id+1) joined
+// by an arc of capacity 1, so each intermediate room carries at most one path.
+// A super source feeds every start room and every end room drains into a
+// super sink, so several start and end rooms are one flow problem.
+type flowNetwork struct {
+	adj    [][]flowEdge
+	source int
+	sink   int
+}
+
+func newFlowNetwork(g *Graph) *flowNetwork {
+	n := len(g.Names)
+	net := &flowNetwork{
+		adj:    make([][]flowEdge, 2*n+2),
+		source: 2 * n,
+		sink:   2*n + 1,
+	}
+	for id := range g.Names {
+		capacity := 1
+		if g.IsStart[id] || g.IsEnd[id] {
+			capacity = n // start and end rooms hold any number of ants
+		}
+		net.addEdge(2*id, 2*id+1, capacity)
+		if g.IsEnd[id] {
+			continue // ants stop at the first end room they reach
+		}
+		for _, neighbor := range g.Neighbors(id) {
+			if !g.IsStart[neighbor] {
+				net.addEdge(2*id+1, 2*neighbor, 1)
+			}
+		}
+	}
+	for _, start := range g.Starts {
+		net.addEdge(net.source, 2*start, n)
+	}
+	for _, end := range g.Ends {
+		net.addEdge(2*end+1, net.sink, n)
+	}
+	return net
+}
+
+func (n *flowNetwork) addEdge(from, to, capacity int) {
+	n.adj[from] = append(n.adj[from], flowEdge{to: to, rev: len(n.adj[to]), cap: capacity, orig: capacity})
+	n.adj[to] = append(n.adj[to], flowEdge{to: from, rev: len(n.adj[from]) - 1})
+}
+
+// augment pushes one more unit of flow along the shortest residual path.
+// It returns false once no augmenting path is left.
+func (n *flowNetwork) augment() bool {
+	type via struct{ node, edge int }
+	prev := make([]via, len(n.adj))
+	for i := range prev {
+		prev[i].node = -1
+	}
+	prev[n.source].node = n.source
+
+	queue := []int{n.source}
+	for len(queue) > 0 && prev[n.sink].node == -1 {
+		current := queue[0]
+		queue = queue[1:]
+		for i, e := range n.adj[current] {
+			if e.cap > 0 && prev[e.to].node == -1 {
+				prev[e.to] = via{current, i}
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	if prev[n.sink].node == -1 {
+		return false
+	}
+
+	for node := n.sink; node != n.source; node = prev[node].node {
+		e := &n.adj[prev[node].node][prev[node].edge]
+		e.cap--
+		n.adj[e.to][e.rev].cap++
+	}
+	return true
+}
+
+// paths walks the current flow from the start rooms to the end rooms and
+// returns it as room paths. Every walk uses up the flow it follows, so a start
+// room carrying several units of flow yields several paths.
+func (n *flowNetwork) paths() [][]int {
+	flow := make([][]int, len(n.adj))
+	for node, edges := range n.adj {
+		flow[node] = make([]int, len(edges))
+		for i, e := range edges {
+			if e.orig > 0 {
+				flow[node][i] = e.orig - e.cap
+			}
+		}
+	}
+	// take uses up one unit of flow leaving node and returns where it goes
+	take := func(node int) int {
+		for i, e := range n.adj[node] {
+			if flow[node][i] > 0 {
+				flow[node][i]--
+				return e.to
+			}
+		}
+		return -1
+	}
+
+	var paths [][]int
+	for node := take(n.source); node != -1; node = take(n.source) {
+		path := []int{}
+		for node != n.sink && node != -1 {
+			room := node / 2
+			if at := slices.Index(path, room); at >= 0 {
+				path = path[:at] // the flow went round a loop, drop it
+			}
+			path = append(path, room)
+
+			if out := take(node); out != -1 { // leave through the out node of this room
+				node = take(out)
+			} else {
+				node = -1
+			}
+		}
+		if node == n.sink {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
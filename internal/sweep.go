@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// SweepRow is how the solver does on a farm for one ant count.
+type SweepRow struct {
+	Ants      int   `json:"ants"`
+	Turns     int   `json:"turns"`      // turns of the printed schedule
+	BestTurns int   `json:"best_turns"` // fewest turns any set of disjoint paths allows, see MinTurns
+	Quotas    []int `json:"quotas"`     // ants sent through every path, the paths carrying none left out
+}
+
+// Sweep parses a farm and solves it again for every ant count in counts, 1 up
+// to the ant count of the farm when counts is empty. The paths are searched
+// once, only the plan and the simulation change from one count to the next.
+func Sweep(ctx context.Context, r io.Reader, counts []int) ([]SweepRow, error) {
+	if err := lockSolver(ctx); err != nil {
+		return nil, err
+	}
+	defer unlockSolver()
+
+	if err := loadForQuery(ctx, r); err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		for n := 1; n <= ants; n++ {
+			counts = append(counts, n)
+		}
+	}
+
+	FindBestPaths(ctx)
+	sets := queryPathSets(ctx)
+	rows := make([]SweepRow, 0, len(counts))
+	for _, n := range counts {
+		if n <= 0 {
+			return nil, errors.New("ant counts must be > 0")
+		}
+		ants = n
+		antGroups = []antGroup{{ants: n}}
+
+		_, quotas, turns := defaultSchedule(ctx)
+		row := SweepRow{Ants: n, Turns: len(turns), BestTurns: minTurnsFor(sets, n)}
+		for _, quota := range quotas {
+			if quota > 0 {
+				row.Quotas = append(row.Quotas, quota)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "tests", "test2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := Sweep(context.Background(), file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 10 {
+		t.Fatalf("got %d rows, want one per ant of the farm", len(rows))
+	}
+	if want := (SweepRow{Ants: 1, Turns: 4, BestTurns: 4, Quotas: []int{1}}); !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("got %+v, want %+v", rows[0], want)
+	}
+	for _, row := range rows {
+		sum := 0
+		for _, q := range row.Quotas {
+			sum += q
+		}
+		if sum != row.Ants || row.Turns < row.BestTurns {
+			t.Errorf("%d ants: quotas %v, %d turns for a best of %d", row.Ants, row.Quotas, row.Turns, row.BestTurns)
+		}
+	}
+	// the full farm is solved as the CLI solves it
+	if rows[9].Turns != 10 {
+		t.Errorf("10 ants: got %d turns, want 10 as in test2.golden", rows[9].Turns)
+	}
+
+	file.Seek(0, 0)
+	rows, err = Sweep(context.Background(), file, []int{50})
+	if err != nil || len(rows) != 1 || rows[0].Ants != 50 {
+		t.Errorf("sweep of 50 ants: got %+v, %v", rows, err)
+	}
+}
+
+func TestSweepGroups(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "tests", "groups.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := Sweep(context.Background(), file, nil); err == nil {
+		t.Error("a farm with ant groups was swept")
+	}
+}
@@ -1,33 +1,100 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
 	"os"
 )
 
-// We use BFS for quick lookup of at least one valid connection start -> end
+// We use BFS for quick lookup of at least one valid connection start -> end.
+// The path it finds is the shortest one and is kept in shortestPath as a
+// fallback for when the full search runs out of time.
 func ValidateConnectivity() {
-	visited := make(map[string]bool) // keep track of rooms visited
-	queue := []string{startRoom}
+	if err := checkConnectivity(); err != nil {
+		Log(err.Error(), "error")
+		os.Exit(0)
+	}
+}
+
+// checkConnectivity is ValidateConnectivity without the exit, for library use.
+// Every start room with its own ant count has to reach an end room, otherwise
+// one start room reaching one is enough.
+func checkConnectivity() error {
+	shortestPath, shortestPaths = nil, nil
+	for _, start := range startRooms {
+		path := bfsPath(start, isEndRoom)
+		if path == nil {
+			if startAnts[start] > 0 {
+				return errors.New("no path from start room " + start + " to an end room")
+			}
+			continue
+		}
+
+		shortestPaths = append(shortestPaths, path)
+		if shortestPath == nil || len(path) < len(shortestPath) {
+			shortestPath = path
+		}
+	}
+
+	// If we finished BFS without finding startRoom -> endRoom
+	if shortestPath == nil {
+		return errors.New("no path from start to end")
+	}
+
+	// a group with its own end room needs a way there
+	for _, group := range antGroups {
+		if group.end != "" && !groupReachable(group) {
+			from := "a start room"
+			if group.start != "" {
+				from = "start room " + group.start
+			}
+			return fmt.Errorf("no path from %s to end room %s", from, group.end)
+		}
+	}
+	return nil
+}
+
+// groupReachable reports whether the end room of group can be reached from
+// its start room, or from any start room when it has none.
+func groupReachable(group antGroup) bool {
+	isGoal := func(room string) bool { return room == group.end }
+	for _, start := range startRooms {
+		if (group.start == "" || group.start == start) && bfsPath(start, isGoal) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bfsPath returns the shortest path from start to the nearest room isTarget
+// accepts, or nil when there is none. Paths never go through another start
+// room or an end room.
+func bfsPath(start string, isTarget func(string) bool) []string {
+	visited := map[string]bool{start: true} // keep track of rooms visited
+	parent := make(map[string]string)
+	queue := []string{start}
 
 	for len(queue) > 0 {
 		current := queue[0]
 		queue = queue[1:]
 
-		if current == endRoom {
-			return // success: path exists
+		if isTarget(current) {
+			// success: path exists, walk the parents back to the start
+			path := []string{current}
+			for room := current; room != start; {
+				room = parent[room]
+				path = append([]string{room}, path...)
+			}
+			return path
 		}
 
-		visited[current] = true
-
 		for _, neighbor := range tunnels[current] { // gives you all rooms connected to the current room
-			if !visited[neighbor] {
+			if !visited[neighbor] && !isStartRoom(neighbor) && (!isEndRoom(neighbor) || isTarget(neighbor)) {
 				queue = append(queue, neighbor)
 				visited[neighbor] = true
+				parent[neighbor] = current
 			}
 		}
 	}
-
-	// If we finished BFS without finding startRoom -> endRoom
-	Log("no path from start to end", "error")
-	os.Exit(0)
+	return nil
 }
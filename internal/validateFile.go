@@ -2,10 +2,13 @@ package internal
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ------------------------------------------------------
@@ -15,40 +18,166 @@ func GetFile() string {
 		os.Exit(0)
 	}
 
-	var (
-		file      string
-		fileFound = false
-	)
+	positional := ParseFlags(os.Args[1:])
+	if len(positional) > 1 {
+		Log("too many positional arguments", "error")
+		os.Exit(0)
+	}
+	if len(positional) == 0 {
+		Log("no input file specified", "error")
+		os.Exit(0)
+	}
+	file := positional[0]
 
-	for _, arg := range os.Args[1:] {
+	// check if file exists
+	_, err := os.Stat(file)
+	if err != nil {
+		Log("file does not exist", "error")
+		os.Exit(0)
+	}
+	return file
+}
+
+// ParseFlags sets the solver options from the command line arguments and
+// returns the positional arguments left over. Unknown flags exit.
+func ParseFlags(arguments []string) []string {
+	var positional []string
+
+	args := splitFlagValues(arguments)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
 		switch arg {
 		case "-v", "--visualize":
 			visualizer = true
 
+		case "--portfolio":
+			portfolio = true
+
+		case "-i", "--interactive":
+			interactive = true
+
+		case "--show-spawns":
+			showSpawns = true
+
+		case "--live":
+			liveAddr = flagValue(args, &i)
+
+		case "--timeout":
+			d, err := time.ParseDuration(flagValue(args, &i))
+			if err != nil || d <= 0 {
+				Log("timeout must be a positive duration such as 500ms or 10s", "error")
+				os.Exit(0)
+			}
+			timeout = d
+
+		case "--max-paths":
+			n, err := strconv.Atoi(flagValue(args, &i))
+			if err != nil || n <= 0 {
+				Log("max-paths must be a number > 0", "error")
+				os.Exit(0)
+			}
+			maxPaths = n
+
+		case "--max-path-len":
+			n, err := strconv.Atoi(flagValue(args, &i))
+			if err != nil || n < 0 {
+				Log("max-path-len must be a number >= 0", "error")
+				os.Exit(0)
+			}
+			maxPathLen = n
+
+		case "--ants-within":
+			n, err := strconv.Atoi(flagValue(args, &i))
+			if err != nil || n <= 0 {
+				Log("ants-within must be a number of turns > 0", "error")
+				os.Exit(0)
+			}
+			antsWithinQuery = n
+
+		case "--turns-for":
+			n, err := strconv.Atoi(flagValue(args, &i))
+			if err != nil || n <= 0 {
+				Log("turns-for must be a number of ants > 0", "error")
+				os.Exit(0)
+			}
+			turnsForQuery = n
+
+		case "--strict":
+			strict = true
+
+		case "--lenient":
+			strict = false // the default, accept what the grammar does not say
+
+		case "--layout":
+			value := flagValue(args, &i)
+			if value != "auto" && value != "input" {
+				Log("layout must be auto or input", "error")
+				os.Exit(0)
+			}
+			layout = value
+
+		case "--objective":
+			value := flagValue(args, &i)
+			if value != "moves" && value != "arrivals" {
+				Log("objective must be moves or arrivals", "error")
+				os.Exit(0)
+			}
+			objective = value
+
+		case "--events":
+			events, err := loadEvents(flagValue(args, &i))
+			if err != nil {
+				Log("events: "+err.Error(), "error")
+				os.Exit(0)
+			}
+			eventScript = events
+
+		case "--release":
+			batches, err := parseRelease(flagValue(args, &i))
+			if err != nil {
+				Log("release: "+err.Error(), "error")
+				os.Exit(0)
+			}
+			releaseFlag = batches
+
 		default:
 			if strings.HasPrefix(arg, "-") {
 				Log(fmt.Sprintf("unknown flag %q", arg), "error")
 				os.Exit(0)
 			}
-			if fileFound {
-				Log("too many positional arguments", "error")
-				os.Exit(0)
-			}
-			file = arg
-			fileFound = true
+			positional = append(positional, arg)
 		}
 	}
-	if !fileFound {
-		Log("no input file specified", "error")
+
+	if interactive && eventScript != nil {
+		Log("--interactive cannot replay --events, use one or the other", "error")
 		os.Exit(0)
 	}
-	// check if file exists
-	_, err := os.Stat(file)
-	if err != nil {
-		Log("file does not exist", "error")
+	return positional
+}
+
+// splitFlagValues turns "--flag=value" into "--flag" "value" so both forms
+// can be handled the same way.
+func splitFlagValues(args []string) []string {
+	split := make([]string, 0, len(args))
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(name, "--") {
+			split = append(split, name, value)
+			continue
+		}
+		split = append(split, arg)
+	}
+	return split
+}
+
+// flagValue consumes the argument following the flag at args[*i].
+func flagValue(args []string, i *int) string {
+	if *i+1 >= len(args) {
+		Log(fmt.Sprintf("flag %q needs a value", args[*i]), "error")
 		os.Exit(0)
 	}
-	return file
+	*i++
+	return args[*i]
 }
 
 // ------------------------------------------------------
@@ -60,26 +189,70 @@ func ValidateFileFormat(filename string) {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	if err := parseFarm(file); err != nil {
+		var lineErr *LineError
+		if errors.As(err, &lineErr) {
+			Log(lineErr.Err.Error(), "error")
+			Log("line "+strconv.Itoa(lineErr.Num)+": "+lineErr.Line, "error")
+		} else {
+			Log(err.Error(), "error")
+		}
+		os.Exit(0) // we already specify the errors so we exiting with a 0 state
+	}
+	Log("Starting Room: "+strings.Join(startRooms, ", ")+" Ending Room: "+strings.Join(endRooms, ", "), "debug")
+}
+
+// LineError is a parse error together with the line of the farm that caused it.
+type LineError struct {
+	Num  int    // line number, starting at 1
+	Line string // the line as it was read
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Num, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// parseFarm reads a whole farm description into the package state.
+func parseFarm(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
 	numLine := 1
 	for scanner.Scan() {
 		line := scanner.Text()
-		Continue := processLine(line, numLine)
-		if Continue != true {
-			Log("line "+strconv.Itoa(numLine)+": "+line, "error")
-			os.Exit(0) // we already specify the errors so we exiting with a 0 state
+		if err := processLine(line, numLine); err != nil {
+			return &LineError{Num: numLine, Line: line, Err: err}
 		}
 		numLine++
 	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if strict && (expectingStartRoom || expectingEndRoom) {
+		return errors.New("the file ends before the room of the last ##start or ##end")
+	}
+	if err := checkDirectives(); err != nil {
+		return err
+	}
 
 	// make sure start && end rooms are not empty strings
 	if strings.TrimSpace(startRoom) == "" {
-		Log("no start room found.", "error")
-		os.Exit(0)
+		return errors.New("no start room found.")
 	}
 	if strings.TrimSpace(endRoom) == "" {
-		Log("no end room found.", "error")
-		os.Exit(0)
+		return errors.New("no end room found.")
+	}
+	if err := buildAntGroups(); err != nil {
+		return err
+	}
+	if err := buildReleases(); err != nil {
+		return err
+	}
+	if err := checkEvents(); err != nil {
+		return err
 	}
-	Log("Starting Room: "+startRoom+" Ending Room: "+endRoom, "debug")
+	return checkGeometry()
 }
@@ -19,7 +19,8 @@ class SimulationViewer:
         self.pos = {}
         for r in self.rooms:
             self.G.add_node(r['name'])
-            self.pos[r['name']] = (r['x'], r['y'])
+            at = r.get('display') or r
+            self.pos[r['name']] = (at['x'], at['y'])
 
         # Infer edges (spawn ⇒ start_room)
         edges = set()
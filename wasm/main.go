@@ -0,0 +1,104 @@
+//go:build js && wasm
+
+// Command wasm exposes the solver to JavaScript for the browser playground.
+// Build it next to index.html with
+//
+//	GOOS=js GOARCH=wasm go build -o wasm/lemin.wasm ./wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" wasm/
+//
+// and serve the directory with any static file server.
+//
+// It registers a global "lemin" object with three functions, each returning a
+// Promise:
+//
+//	lemin.validate(farm)      -> the parsed farm: ants, rooms, tunnels, starts, ends
+//	lemin.solve(farm, [ms])   -> the result: turns, lower_bound, paths, moves, timed_out, ...
+//	lemin.check(farm, moves)  -> {valid, error} for one line of moves per turn
+//
+// The page has a single thread, so solve gives the search a time budget, ms
+// milliseconds or solveTimeout, and keeps the best schedule found by then
+// with timed_out set. A broken farm rejects the Promise with an Error carrying
+// the message the CLI would print.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"lemin/internal"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// solveTimeout bounds lemin.solve when the page gives no time budget, a dense
+// farm would freeze the tab otherwise.
+const solveTimeout = 5 * time.Second
+
+func main() {
+	internal.SetQuiet(true) // the answers go back to the page, not to the console
+
+	js.Global().Set("lemin", js.ValueOf(map[string]any{
+		"validate": promised(func(args []js.Value) (any, error) {
+			return internal.Validate(strings.NewReader(arg(args, 0)))
+		}),
+		"solve": promised(func(args []js.Value) (any, error) {
+			timeout := solveTimeout
+			if len(args) > 1 && args[1].Type() == js.TypeNumber && args[1].Float() > 0 {
+				timeout = time.Duration(args[1].Float() * float64(time.Millisecond))
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return internal.Solve(ctx, strings.NewReader(arg(args, 0)))
+		}),
+		"check": promised(func(args []js.Value) (any, error) {
+			var moves []string
+			if len(args) > 1 && args[1].Type() == js.TypeObject {
+				for i := 0; i < args[1].Length(); i++ {
+					moves = append(moves, args[1].Index(i).String())
+				}
+			}
+			// a broken rule is an answer, not a failure, like POST /check
+			if err := internal.Check(strings.NewReader(arg(args, 0)), moves); err != nil {
+				return map[string]any{"valid": false, "error": err.Error()}, nil
+			}
+			return map[string]any{"valid": true}, nil
+		}),
+	}))
+
+	select {} // keep the functions alive
+}
+
+// arg returns args[i] as a string, "" when it is missing.
+func arg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// promised wraps fn into a JavaScript function returning a Promise. fn runs in
+// its own goroutine: the solver may wait on timers and goroutines, which
+// cannot happen while a JavaScript call is blocked.
+func promised(fn func(args []js.Value) (any, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		executor := js.FuncOf(func(this js.Value, handlers []js.Value) any {
+			resolve, reject := handlers[0], handlers[1]
+			go func() {
+				v, err := fn(args)
+				if err != nil {
+					reject.Invoke(js.Global().Get("Error").New(err.Error()))
+					return
+				}
+				data, err := json.Marshal(v)
+				if err != nil {
+					reject.Invoke(js.Global().Get("Error").New(err.Error()))
+					return
+				}
+				resolve.Invoke(js.Global().Get("JSON").Call("parse", string(data)))
+			}()
+			return nil
+		})
+		defer executor.Release()
+		return js.Global().Get("Promise").New(executor)
+	})
+}